@@ -0,0 +1,169 @@
+// Command apikey creates, lists and revokes the API keys of the search
+// server. It connects to MongoDB with the same configuration as the server.
+//
+//	apikey create -name <name> -role reader|ingestor|admin [-quota search=N,ingestion=N]
+//	apikey list
+//	apikey revoke <id>
+//	apikey quota <id> search=N,ingestion=N
+//
+// A key is only shown when it is created; the server stores its hash. Quotas
+// override the configured daily request quota of a route group for one key;
+// 0 removes the quota.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/auth"
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/ratelimit"
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configurations: %v", err)
+	}
+	db, err := mongo.SetupDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Error setting up database: %v", err)
+	}
+	defer db.Client.Disconnect(context.Background())
+
+	switch flag.Arg(0) {
+	case "create":
+		err = create(db, flag.Args()[1:])
+	case "list":
+		err = list(db)
+	case "quota":
+		if flag.NArg() != 3 {
+			usage()
+			os.Exit(2)
+		}
+		err = setQuotas(db, flag.Arg(1), flag.Arg(2))
+	case "revoke":
+		if flag.NArg() != 2 {
+			usage()
+			os.Exit(2)
+		}
+		err = db.RevokeAPIKey(flag.Arg(1))
+		if err == nil {
+			fmt.Printf("Revoked API key %s\n", flag.Arg(1))
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s create -name <name> -role %s [-quota group=N,...] | list | revoke <id> | quota <id> group=N,...\n", os.Args[0], strings.Join(auth.Roles, "|"))
+}
+
+func create(db *mongo.Database, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	name := flags.String("name", "", "name of the key owner, such as a team or service")
+	role := flags.String("role", auth.RoleReader, "role of the key: "+strings.Join(auth.Roles, ", "))
+	quota := flags.String("quota", "", "daily quotas overriding the configured ones, such as search=50000,ingestion=100")
+	flags.Parse(args)
+	if *name == "" {
+		return fmt.Errorf("-name is required")
+	}
+	quotas, err := parseQuotas(*quota)
+	if err != nil {
+		return err
+	}
+
+	if err := db.EnsureAPIKeys(); err != nil {
+		return err
+	}
+	key, record, err := auth.GenerateKey(*name, *role)
+	if err != nil {
+		return err
+	}
+	record.DailyQuotas = quotas
+	id, err := db.StoreAPIKey(record)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %s key %s for %s. Store it now, it is not shown again:\n\n%s\n", record.Role, id, record.Name, key)
+	return nil
+}
+
+func list(db *mongo.Database) error {
+	keys, err := db.ListAPIKeys()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tROLE\tPREFIX\tQUOTAS\tCREATED\tREVOKED")
+	for _, key := range keys {
+		revoked := "-"
+		if key.RevokedAt != nil {
+			revoked = key.RevokedAt.Format(time.RFC3339)
+		}
+		quotas := make([]string, 0, len(key.DailyQuotas))
+		for _, group := range ratelimit.Groups {
+			if quota, ok := key.DailyQuotas[group]; ok {
+				quotas = append(quotas, fmt.Sprintf("%s=%d", group, quota))
+			}
+		}
+		if len(quotas) == 0 {
+			quotas = append(quotas, "-")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID.Hex(), key.Name, key.Role, key.Prefix, strings.Join(quotas, ","), key.CreatedAt.Format(time.RFC3339), revoked)
+	}
+	return w.Flush()
+}
+
+func setQuotas(db *mongo.Database, id, list string) error {
+	quotas, err := parseQuotas(list)
+	if err != nil {
+		return err
+	}
+	if err := db.SetAPIKeyQuotas(id, quotas); err != nil {
+		return err
+	}
+	fmt.Printf("Updated the quotas of API key %s\n", id)
+	return nil
+}
+
+// parseQuotas parses a comma-separated list of group=quota pairs.
+func parseQuotas(list string) (map[string]int64, error) {
+	if list == "" {
+		return nil, nil
+	}
+	quotas := map[string]int64{}
+	for _, pair := range strings.Split(list, ",") {
+		group, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || !slices.Contains(ratelimit.Groups, group) {
+			return nil, fmt.Errorf("invalid quota %q, expected <group>=<requests per day> with a group among %v", pair, ratelimit.Groups)
+		}
+		quota, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || quota < 0 {
+			return nil, fmt.Errorf("invalid quota %q, expected a non-negative number of requests", pair)
+		}
+		quotas[group] = quota
+	}
+	return quotas, nil
+}
@@ -8,25 +8,44 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
-	_ "github.com/avyukth/search-app/docs"
+	"github.com/avyukth/search-app/docs"
 	appTrace "github.com/avyukth/search-app/foundations/tracing"
+	"github.com/avyukth/search-app/pkg/alert"
+	"github.com/avyukth/search-app/pkg/analytics"
+	"github.com/avyukth/search-app/pkg/api/gql"
+	"github.com/avyukth/search-app/pkg/api/openapi"
+	"github.com/avyukth/search-app/pkg/api/problem"
 	"github.com/avyukth/search-app/pkg/api/router"
+	"github.com/avyukth/search-app/pkg/api/rpc"
+	"github.com/avyukth/search-app/pkg/cache"
 	"github.com/avyukth/search-app/pkg/config"
 	"github.com/avyukth/search-app/pkg/database/mongo"
 	"github.com/avyukth/search-app/pkg/downloader"
+	"github.com/avyukth/search-app/pkg/events"
+	"github.com/avyukth/search-app/pkg/health"
 	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/avyukth/search-app/pkg/ingest"
 	"github.com/avyukth/search-app/pkg/parser"
 	"github.com/avyukth/search-app/pkg/queue"
+	"github.com/avyukth/search-app/pkg/ratelimit"
+	"github.com/avyukth/search-app/pkg/snapshot"
+	"github.com/avyukth/search-app/pkg/upload"
 	"github.com/avyukth/search-app/pkg/worker"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/swagger"
+	"google.golang.org/grpc"
 	// "github.com/joho/godotenv"
 )
 
+// grpcShutdownTimeout bounds how long gRPC calls may run after the shutdown
+// signal.
+const grpcShutdownTimeout = 10 * time.Second
+
 type AppConfig struct {
 	ListenAddr   string
 	ServiceName string
@@ -116,26 +135,53 @@ type AppConfig struct {
 // 	waitForShutdownSignal(app)
 
 // }
+
+// @title Patent Search API
+// @version 1.0
+// @description Search, ingestion and administration of USPTO design patent grants.
+// @BasePath /api/v1
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 	cfg := loadConfig()
-	ctx := setupContext()
+	ctx, stop := setupContext()
+	defer stop()
 
 	otelShutdown := setupTracing(ctx, cfg)
-	defer shutdownTracing(ctx, otelShutdown)
+	// ctx is cancelled by the shutdown signal, so cleanup gets its own context
+	defer shutdownTracing(context.Background(), otelShutdown)
 
 	db := setupDatabase(cfg)
-	defer db.Client.Disconnect(ctx)
+	defer db.Client.Disconnect(context.Background())
+	queryLog := setupQueryLog(db)
+	defer queryLog.Close()
+	setupIdempotencyKeys(db)
+	setupPatentIndexes(db)
+	setupAlertIndexes(db)
+	setupAPIKeys(db, cfg)
 
 	httpClient, parser, indexer := initializeComponents(cfg)
-	q := setupWorkerComponents(httpClient, parser, db, indexer, cfg)
+	defer indexer.Close()
+	snapshots := setupSnapshots(indexer, cfg)
+	broker := events.NewBroker()
+	alerts := setupAlerts(db, indexer, cfg)
+	defer alerts.Close()
+	q := setupWorkerComponents(ctx, httpClient, parser, db, indexer, alerts, broker, cfg)
 	defer q.Stop()
 
 	app := setupFiberApp(cfg)
-	router.SetupRoutes(app, db, indexer, q)
+	uploads := upload.NewStore(filepath.Join(cfg.ServerConfig.Storage, cfg.UploadConfig.Directory), cfg.UploadConfig.MaxBytes)
+	limits := setupRateLimits(db, cfg)
+	ingestions := ingest.NewSubmitter(db, q, httpClient)
+	router.SetupRoutes(app, db, indexer, q, ingestions, snapshots, queryLog, broker, uploads, cfg.AuthConfig.Enabled, limits, setupGraphQL(db, indexer, cfg), setupOpenAPI(), health.NewChecker(db, indexer, q, cfg))
+	grpcServer := setupGRPCServer(db, indexer, ingestions, queryLog, broker, limits, cfg)
 
 
 	go startApp(app, cfg.ServerConfig)
+	go startGRPCServer(grpcServer, cfg.ServerConfig)
 	waitForShutdownSignal(app)
+	stopGRPCServer(grpcServer)
 }
 
 func loadConfig() *config.Config {
@@ -146,10 +192,8 @@ func loadConfig() *config.Config {
 	return cfg
 }
 
-func setupContext() context.Context {
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer stop()
-	return ctx
+func setupContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
 }
 
 func setupTracing(ctx context.Context, cfg *config.Config) func(context.Context) error {
@@ -174,24 +218,128 @@ func setupDatabase(cfg *config.Config) *mongo.Database {
 	return db
 }
 
-func initializeComponents(cfg *config.Config) (*http.Client, *parser.Parser, *indexer.SearchEngine) {
+func setupQueryLog(db *mongo.Database) *analytics.Recorder {
+	if err := db.EnsureQueryLog(); err != nil {
+		log.Fatalf("Error setting up query log: %v", err)
+	}
+	return analytics.NewRecorder(db, analytics.DefaultBufferSize)
+}
+
+func setupAPIKeys(db *mongo.Database, cfg *config.Config) {
+	if err := db.EnsureAPIKeys(); err != nil {
+		log.Fatalf("Error setting up API keys: %v", err)
+	}
+	if !cfg.AuthConfig.Enabled {
+		log.Println("API key authentication is disabled, every route is open")
+	}
+}
+
+func setupRateLimits(db *mongo.Database, cfg *config.Config) *ratelimit.Limits {
+	if err := db.EnsureQuotas(); err != nil {
+		log.Fatalf("Error setting up quotas: %v", err)
+	}
+	return ratelimit.NewLimits(db, &cfg.RateLimitConfig)
+}
+
+func setupGraphQL(db *mongo.Database, indexer indexer.SearchBackend, cfg *config.Config) *gql.Schema {
+	schema, err := gql.NewSchema(db, indexer, gql.Limits{
+		MaxDepth: cfg.GraphQLConfig.MaxDepth,
+		MaxCost:  cfg.GraphQLConfig.MaxCost,
+	})
+	if err != nil {
+		log.Fatalf("Error setting up GraphQL: %v", err)
+	}
+	return schema
+}
+
+func setupOpenAPI() *openapi.Spec {
+	spec, err := openapi.Load(docs.SwaggerInfo.ReadDoc())
+	if err != nil {
+		log.Fatalf("Error loading OpenAPI spec: %v", err)
+	}
+	return spec
+}
+
+func setupIdempotencyKeys(db *mongo.Database) {
+	if err := db.EnsureIdempotencyKeys(); err != nil {
+		log.Fatalf("Error setting up idempotency keys: %v", err)
+	}
+}
+
+func setupPatentIndexes(db *mongo.Database) {
+	if err := db.EnsurePatentIndexes(); err != nil {
+		log.Fatalf("Error setting up patent indexes: %v", err)
+	}
+}
+
+func setupAlertIndexes(db *mongo.Database) {
+	if err := db.EnsureAlertIndexes(); err != nil {
+		log.Fatalf("Error setting up alert indexes: %v", err)
+	}
+}
+
+func initializeComponents(cfg *config.Config) (*http.Client, *parser.Parser, indexer.SearchBackend) {
 	httpClient := &http.Client{Timeout: 30 * time.Second}
 	parser := parser.NewParser()
-	indexer, err := indexer.NewSearchEngine(cfg.ServerConfig.Storage + cfg.ServerConfig.IndexDirectory)
+	indexer, err := indexer.NewSearchBackend(cfg)
 	if err != nil {
 		log.Fatalf("Error initializing indexer: %v", err)
 	}
+	resultCache, err := cache.NewCache(cfg)
+	if err != nil {
+		log.Fatalf("Error initializing search cache: %v", err)
+	}
+	if resultCache != nil {
+		indexer = cache.NewCachedBackend(indexer, resultCache)
+	}
 	return httpClient, parser, indexer
 }
 
-func setupWorkerComponents(httpClient *http.Client, parser *parser.Parser, db *mongo.Database, indexer *indexer.SearchEngine, cfg *config.Config) (*queue.TaskQueue) {
+func setupSnapshots(indexer indexer.SearchBackend, cfg *config.Config) *snapshot.Manager {
+	snapshots := snapshot.NewManager(cfg.SnapshotConfig.Directory, indexer)
+	if cfg.SnapshotConfig.Restore != "" {
+		manifest, err := snapshots.Restore(cfg.SnapshotConfig.Restore)
+		if err != nil {
+			log.Fatalf("Error restoring snapshot: %v", err)
+		}
+		log.Printf("Restored index snapshot %s taken at %s", manifest.Name, manifest.CreatedAt)
+	}
+	return snapshots
+}
+
+// setupWorkerComponents starts the task queue. Workers run until ctx is
+// cancelled or the queue is stopped.
+func setupWorkerComponents(ctx context.Context, httpClient *http.Client, parser *parser.Parser, db *mongo.Database, indexer indexer.SearchBackend, alerts *alert.Evaluator, broker *events.Broker, cfg *config.Config) *queue.TaskQueue {
 	dl := downloader.NewDownloader(httpClient, &cfg.ServerConfig)
-	wk := worker.NewWorker(dl, parser, db, indexer)
-	q := queue.NewTaskQueue(10, wk)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	wk := worker.NewWorker(dl, parser, db, indexer, alerts, broker)
+	q := queue.NewTaskQueue(10, cfg.QueueConfig.TaskTimeout, wk)
 	q.Start(ctx)
-	return  q
+	return q
+}
+
+// setupAlerts returns the saved search evaluator, delivering alerts in the
+// background when a webhook is configured. The workers must be stopped before
+// it is closed.
+func setupAlerts(db *mongo.Database, indexer indexer.SearchBackend, cfg *config.Config) *alert.Evaluator {
+	var dispatcher *alert.Dispatcher
+	if cfg.AlertConfig.WebhookURL != "" {
+		notifier := alert.NewWebhookNotifier(&http.Client{Timeout: cfg.AlertConfig.WebhookTimeout}, cfg.AlertConfig.WebhookURL)
+		dispatcher = alert.NewDispatcher(db, notifier, alert.RetryPolicy{
+			Attempts:  cfg.AlertConfig.WebhookAttempts,
+			Backoff:   cfg.AlertConfig.WebhookBackoff,
+			QueueSize: cfg.AlertConfig.QueueSize,
+		})
+	}
+	return alert.NewEvaluator(db, indexer, dispatcher)
+}
+
+// setupGRPCServer returns the gRPC API, sharing the instances and rate limits
+// of the HTTP API, or nil when it is disabled.
+func setupGRPCServer(db *mongo.Database, indexer indexer.SearchBackend, ingestions *ingest.Submitter, queryLog *analytics.Recorder, broker *events.Broker, limits *ratelimit.Limits, cfg *config.Config) *grpc.Server {
+	if cfg.ServerConfig.GRPCPort == 0 {
+		return nil
+	}
+	return rpc.NewGRPCServer(rpc.NewServer(db, indexer, ingestions, queryLog, broker), cfg.AuthConfig.Enabled, limits)
 }
 
 func setupFiberApp(cfg *config.Config) *fiber.App {
@@ -203,11 +351,16 @@ func setupFiberApp(cfg *config.Config) *fiber.App {
 		ReadTimeout:           time.Second,
 		WriteTimeout:          10 * time.Second,
 		DisableStartupMessage: false,
+		// Request bodies are streamed to the handler so that uploads are not
+		// read into memory; other routes are bounded by handler.LimitBody.
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+		// Errors returned by handlers are sent as problem details.
+		ErrorHandler: problem.ErrorHandler,
 	})
-	app.Static("/docs", "./docs")
 	app.Use(cors.New())
-	swaggerURL := "/docs/swagger.yaml"
-	app.Get("/swagger/*", swagger.New(swagger.Config{URL: swaggerURL}))
+	// The UI loads the spec compiled into the binary from /swagger/doc.json.
+	app.Get("/swagger/*", swagger.HandlerDefault)
 	return app
 }
 
@@ -229,6 +382,34 @@ func waitForShutdownSignal(app *fiber.App) {
 }
 
 
+func startGRPCServer(server *grpc.Server, cfg config.ServerConfig) {
+	if server == nil {
+		return
+	}
+	if err := rpc.Serve(server, cfg.GRPCPort); err != nil {
+		log.Fatalf("Failed to start gRPC server: %v", err)
+	}
+}
+
+// stopGRPCServer waits for running calls to finish, cutting off the WatchJob
+// streams still open after grpcShutdownTimeout.
+func stopGRPCServer(server *grpc.Server) {
+	if server == nil {
+		return
+	}
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(grpcShutdownTimeout):
+		server.Stop()
+	}
+	log.Println("gRPC server shutdown complete")
+}
+
 func startApp(app *fiber.App, cfg config.ServerConfig) {
 	addr:= fmt.Sprintf(":%d", cfg.ServicePort)
 	if err := app.Listen(addr); err != nil {
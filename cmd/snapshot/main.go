@@ -0,0 +1,101 @@
+// Command snapshot creates, lists and restores index snapshots through the
+// admin API of a running server.
+//
+//	snapshot [-addr URL] create
+//	snapshot [-addr URL] list
+//	snapshot [-addr URL] restore <name>
+//
+// -addr defaults to the SERVER_HOST and SERVER_PORT the server listens on,
+// read from the environment like the server does. The admin API key is read
+// from -key or the SEARCH_API_KEY environment variable.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/config"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configurations: %v", err)
+	}
+	defaultAddr := fmt.Sprintf("http://%s:%d", cfg.ServerConfig.ServiceHost, cfg.ServerConfig.ServicePort)
+
+	addr := flag.String("addr", defaultAddr, "base URL of the search server")
+	timeout := flag.Duration("timeout", 10*time.Minute, "request timeout")
+	key := flag.String("key", os.Getenv("SEARCH_API_KEY"), "admin API key")
+	flag.Usage = usage
+	flag.Parse()
+
+	client := &http.Client{Timeout: *timeout}
+	base := strings.TrimSuffix(*addr, "/") + "/api/v1/admin/snapshots"
+
+	var method, endpoint string
+	switch flag.Arg(0) {
+	case "create":
+		method, endpoint = http.MethodPost, base
+	case "list":
+		method, endpoint = http.MethodGet, base
+	case "restore":
+		if flag.NArg() != 2 {
+			usage()
+			os.Exit(2)
+		}
+		method, endpoint = http.MethodPost, base+"/"+url.PathEscape(flag.Arg(1))+"/restore"
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err := call(client, method, endpoint, *key); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] create | list | restore <name>\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+// call sends the request and prints the indented JSON response.
+func call(client *http.Client, method, endpoint, key string) error {
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %v", err)
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		out.Reset()
+		out.Write(body)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s: %s", resp.Status, out.String())
+	}
+	fmt.Println(out.String())
+	return nil
+}
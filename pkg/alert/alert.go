@@ -0,0 +1,143 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+)
+
+// Notifier delivers alerts to an external receiver.
+type Notifier interface {
+	Notify(ctx context.Context, alert *mongo.Alert) error
+}
+
+// Store records saved searches and alerts. It is implemented by
+// *mongo.Database.
+type Store interface {
+	AllSavedSearches() ([]mongo.SavedSearch, error)
+	StoreAlert(alert *mongo.Alert) (string, error)
+	MarkAlertDelivered(id string, deliveryErr error) error
+}
+
+// StatusError is returned by WebhookNotifier when the receiver answers with a
+// non-2xx status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "non-2xx status code received from webhook: " + e.Status
+}
+
+// WebhookNotifier delivers alerts by POSTing them as JSON to a fixed URL.
+type WebhookNotifier struct {
+	client *http.Client
+	url    string
+}
+
+func NewWebhookNotifier(client *http.Client, url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		client: client,
+		url:    url,
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert *mongo.Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("marshalling alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// Evaluator matches newly indexed patents against saved searches, records an
+// alert for every match and queues it for delivery. A patent that is indexed
+// again does not alert the same saved search twice.
+type Evaluator struct {
+	store      Store
+	engine     indexer.SearchBackend
+	dispatcher *Dispatcher
+}
+
+// NewEvaluator creates an Evaluator. A nil dispatcher records alerts without
+// delivering them.
+func NewEvaluator(store Store, engine indexer.SearchBackend, dispatcher *Dispatcher) *Evaluator {
+	return &Evaluator{
+		store:      store,
+		engine:     engine,
+		dispatcher: dispatcher,
+	}
+}
+
+// Evaluate must be called after the patent has been indexed. Alerts are
+// delivered in the background.
+func (e *Evaluator) Evaluate(patent *mongo.Patent) error {
+	searches, err := e.store.AllSavedSearches()
+	if err != nil {
+		return err
+	}
+
+	for _, search := range searches {
+		matched, err := e.engine.MatchesPatent(patent.PatentStorageID, search.Query, search.Filters)
+		if err != nil {
+			log.Printf("Error evaluating saved search %q: %v", search.Name, err)
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		alert := &mongo.Alert{
+			SavedSearchID:   search.ID,
+			APIKeyID:        search.APIKeyID,
+			SavedSearchName: search.Name,
+			PatentStorageID: patent.PatentStorageID,
+			PatentNumber:    patent.PatentNumber,
+			PatentTitle:     patent.PatentTitle,
+		}
+		alertID, err := e.store.StoreAlert(alert)
+		if errors.Is(err, mongo.ErrAlreadyAlerted) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("Patent %s matched saved search %q", patent.PatentNumber, search.Name)
+
+		if e.dispatcher != nil {
+			e.dispatcher.Enqueue(alertID, alert)
+		}
+	}
+	return nil
+}
+
+// Close delivers the queued alerts and stops the dispatcher, if any.
+func (e *Evaluator) Close() {
+	if e.dispatcher != nil {
+		e.dispatcher.Close()
+	}
+}
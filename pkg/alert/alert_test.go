@@ -0,0 +1,384 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memStore is an in-memory Store.
+type memStore struct {
+	mu       sync.Mutex
+	searches []mongo.SavedSearch
+	alerts   map[string]*mongo.Alert
+	// recorded counts the delivery outcomes recorded.
+	recorded int
+}
+
+func newMemStore(searches ...mongo.SavedSearch) *memStore {
+	for i := range searches {
+		searches[i].ID = primitive.NewObjectID()
+	}
+	return &memStore{searches: searches, alerts: make(map[string]*mongo.Alert)}
+}
+
+func (s *memStore) AllSavedSearches() ([]mongo.SavedSearch, error) {
+	return s.searches, nil
+}
+
+func (s *memStore) StoreAlert(alert *mongo.Alert) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stored := range s.alerts {
+		if stored.SavedSearchID == alert.SavedSearchID && stored.PatentStorageID == alert.PatentStorageID {
+			return "", mongo.ErrAlreadyAlerted
+		}
+	}
+	alert.ID = primitive.NewObjectID()
+	stored := *alert
+	s.alerts[alert.ID.Hex()] = &stored
+	return alert.ID.Hex(), nil
+}
+
+func (s *memStore) MarkAlertDelivered(id string, deliveryErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	alert, ok := s.alerts[id]
+	if !ok {
+		return mongo.ErrNotFound
+	}
+	s.recorded++
+	alert.Delivered = deliveryErr == nil
+	alert.DeliveryError = ""
+	if deliveryErr != nil {
+		alert.DeliveryError = deliveryErr.Error()
+	}
+	return nil
+}
+
+// waitRecorded waits for n delivery outcomes to be recorded.
+func (s *memStore) waitRecorded(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		recorded := s.recorded
+		s.mu.Unlock()
+		if recorded >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d delivery outcomes recorded, want %d", recorded, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (s *memStore) alert(id string) mongo.Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.alerts[id]
+}
+
+func (s *memStore) all() []mongo.Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	alerts := make([]mongo.Alert, 0, len(s.alerts))
+	for _, alert := range s.alerts {
+		alerts = append(alerts, *alert)
+	}
+	return alerts
+}
+
+// receiver is a local webhook receiver answering with the given statuses in
+// turn, then with 204.
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	received []mongo.Alert
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q, want a JSON POST", req.Method, req.Header.Get("Content-Type"))
+		}
+		var alert mongo.Alert
+		if err := json.NewDecoder(req.Body).Decode(&alert); err != nil {
+			t.Errorf("decoding alert: %v", err)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.received = append(r.received, alert)
+		status := http.StatusNoContent
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.received)
+}
+
+func (r *receiver) alerts() []mongo.Alert {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]mongo.Alert(nil), r.received...)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus int
+	}{
+		{"accepted", http.StatusAccepted, 0},
+		{"no content", http.StatusNoContent, 0},
+		{"rejected", http.StatusBadRequest, http.StatusBadRequest},
+		{"unavailable", http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newReceiver(t, tt.status)
+			alert := &mongo.Alert{SavedSearchName: "chairs", PatentNumber: "D0900001"}
+			err := NewWebhookNotifier(r.Client(), r.URL).Notify(context.Background(), alert)
+
+			var statusErr *StatusError
+			switch {
+			case tt.wantStatus == 0 && err != nil:
+				t.Fatalf("Notify() = %v, want nil", err)
+			case tt.wantStatus != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus):
+				t.Fatalf("Notify() = %v, want a %d StatusError", err, tt.wantStatus)
+			}
+			if received := r.alerts(); len(received) != 1 || received[0].PatentNumber != "D0900001" {
+				t.Errorf("received %+v, want the alert once", received)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierUnreachable(t *testing.T) {
+	r := newReceiver(t)
+	r.Close()
+	err := NewWebhookNotifier(http.DefaultClient, r.URL).Notify(context.Background(), &mongo.Alert{})
+	if err == nil || !retryable(err) {
+		t.Fatalf("Notify() = %v, want a retryable error", err)
+	}
+}
+
+func newEngine(t *testing.T, patents ...*mongo.Patent) indexer.SearchBackend {
+	t.Helper()
+	indexMapping, err := indexer.NewIndexMapping(&config.AnalyzerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := indexer.NewMemSearchEngine(indexMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	for _, patent := range patents {
+		if err := engine.IndexPatent(patent); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return engine
+}
+
+func TestEvaluate(t *testing.T) {
+	patent := &mongo.Patent{
+		PatentStorageID: "chair-1",
+		PatentNumber:    "D0900001",
+		PatentTitle:     "Office chair",
+		AssigneeName:    "Herman Miller",
+	}
+	engine := newEngine(t, patent)
+	store := newMemStore(
+		mongo.SavedSearch{Name: "chairs", Query: "chair"},
+		mongo.SavedSearch{Name: "tables", Query: "table"},
+		mongo.SavedSearch{Name: "miller chairs", Query: "chair", Filters: map[string]string{"AssigneeName": "Herman Miller"}},
+		mongo.SavedSearch{Name: "other chairs", Query: "chair", Filters: map[string]string{"AssigneeName": "Steelcase"}},
+	)
+	// The first delivery fails and is retried.
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	d := NewDispatcher(store, NewWebhookNotifier(r.Client(), r.URL), RetryPolicy{Attempts: 3, Backoff: time.Millisecond, QueueSize: 10})
+	defer d.Close()
+
+	if err := NewEvaluator(store, engine, d).Evaluate(patent); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	store.waitRecorded(t, 2)
+
+	matched := map[string]bool{}
+	for _, alert := range store.all() {
+		matched[alert.SavedSearchName] = true
+		if !alert.Delivered || alert.DeliveryError != "" {
+			t.Errorf("alert for %q not delivered: %q", alert.SavedSearchName, alert.DeliveryError)
+		}
+		if alert.PatentNumber != patent.PatentNumber {
+			t.Errorf("alert for %q has patent %q", alert.SavedSearchName, alert.PatentNumber)
+		}
+	}
+	if len(matched) != 2 || !matched["chairs"] || !matched["miller chairs"] {
+		t.Errorf("alerts for %v, want chairs and miller chairs", matched)
+	}
+	if r.calls() != 3 {
+		t.Errorf("receiver called %d times, want 2 alerts and 1 retry", r.calls())
+	}
+}
+
+func TestEvaluateWithoutDispatcher(t *testing.T) {
+	patent := &mongo.Patent{PatentStorageID: "chair-1", PatentTitle: "Office chair"}
+	store := newMemStore(mongo.SavedSearch{Name: "chairs", Query: "chair"})
+	if err := NewEvaluator(store, newEngine(t, patent), nil).Evaluate(patent); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if alerts := store.all(); len(alerts) != 1 || alerts[0].Delivered {
+		t.Errorf("alerts = %+v, want one undelivered alert", alerts)
+	}
+}
+
+func TestEvaluateReindexedPatent(t *testing.T) {
+	patent := &mongo.Patent{PatentStorageID: "chair-1", PatentTitle: "Office chair"}
+	store := newMemStore(mongo.SavedSearch{APIKeyID: "key-1", Name: "chairs", Query: "chair"})
+	r := newReceiver(t)
+	d := NewDispatcher(store, NewWebhookNotifier(r.Client(), r.URL), RetryPolicy{Attempts: 1, QueueSize: 10})
+	defer d.Close()
+
+	e := NewEvaluator(store, newEngine(t, patent), d)
+	for i := 0; i < 2; i++ {
+		if err := e.Evaluate(patent); err != nil {
+			t.Fatalf("Evaluate #%d: %v", i+1, err)
+		}
+	}
+	store.waitRecorded(t, 1)
+
+	alerts := store.all()
+	if len(alerts) != 1 {
+		t.Fatalf("%d alerts recorded, want 1", len(alerts))
+	}
+	if alerts[0].APIKeyID != "key-1" {
+		t.Errorf("alert API key ID = %q, want the one of the saved search", alerts[0].APIKeyID)
+	}
+	if r.calls() != 1 {
+		t.Errorf("receiver called %d times, want 1", r.calls())
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		statuses      []int
+		wantCalls     int
+		wantDelivered bool
+	}{
+		{"first attempt", nil, 1, true},
+		{"after retries", []int{500, 503}, 3, true},
+		{"rate limited", []int{429}, 2, true},
+		{"attempts exhausted", []int{500, 500, 500, 500}, 3, false},
+		{"rejected", []int{400}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newReceiver(t, tt.statuses...)
+			store := newMemStore()
+			alert := &mongo.Alert{PatentNumber: "D0900001"}
+			id, _ := store.StoreAlert(alert)
+
+			d := NewDispatcher(store, NewWebhookNotifier(r.Client(), r.URL), RetryPolicy{Attempts: 3, Backoff: time.Millisecond, QueueSize: 1})
+			defer d.Close()
+			d.Enqueue(id, alert)
+			store.waitRecorded(t, 1)
+
+			if r.calls() != tt.wantCalls {
+				t.Errorf("receiver called %d times, want %d", r.calls(), tt.wantCalls)
+			}
+			got := store.alert(id)
+			if got.Delivered != tt.wantDelivered || (got.DeliveryError == "") != tt.wantDelivered {
+				t.Errorf("alert delivered = %v with error %q, want delivered = %v", got.Delivered, got.DeliveryError, tt.wantDelivered)
+			}
+		})
+	}
+}
+
+// blockingNotifier blocks every delivery until released.
+type blockingNotifier struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, alert *mongo.Alert) error {
+	n.started <- struct{}{}
+	<-n.release
+	return nil
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	store := newMemStore()
+	notifier := &blockingNotifier{started: make(chan struct{}, 3), release: make(chan struct{})}
+	d := NewDispatcher(store, notifier, RetryPolicy{Attempts: 1, QueueSize: 1})
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		alert := &mongo.Alert{PatentStorageID: strconv.Itoa(i)}
+		id, _ := store.StoreAlert(alert)
+		ids = append(ids, id)
+		d.Enqueue(id, alert)
+		if i == 0 {
+			// Wait for the first alert to leave the queue.
+			<-notifier.started
+		}
+	}
+	close(notifier.release)
+	d.Close()
+
+	for i, want := range []string{"", "", errQueueFull.Error()} {
+		if got := store.alert(ids[i]).DeliveryError; got != want {
+			t.Errorf("alert %d delivery error = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestDispatcherCloseStopsRetrying(t *testing.T) {
+	r := newReceiver(t, 500, 500, 500)
+	store := newMemStore()
+	alert := &mongo.Alert{}
+	id, _ := store.StoreAlert(alert)
+	d := NewDispatcher(store, NewWebhookNotifier(r.Client(), r.URL), RetryPolicy{Attempts: 3, Backoff: time.Hour, QueueSize: 1})
+	d.Enqueue(id, alert)
+
+	closed := make(chan struct{})
+	go func() {
+		d.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close waited for the backoff")
+	}
+	if got := store.alert(id); got.Delivered || got.DeliveryError == "" {
+		t.Errorf("alert = %+v, want it recorded undelivered", got)
+	}
+}
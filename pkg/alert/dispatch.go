@@ -0,0 +1,136 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+)
+
+// maxBackoff caps the wait between two delivery attempts.
+const maxBackoff = 5 * time.Minute
+
+// errQueueFull is recorded on alerts dropped because the delivery queue is
+// full.
+var errQueueFull = errors.New("alert delivery queue is full")
+
+// RetryPolicy bounds the delivery of an alert. The wait before a retry starts
+// at Backoff and doubles after every failed attempt.
+type RetryPolicy struct {
+	// Attempts is the number of deliveries tried before giving up.
+	Attempts int
+	Backoff  time.Duration
+	// QueueSize is the number of alerts waiting for delivery above which new
+	// alerts are left undelivered.
+	QueueSize int
+}
+
+// Dispatcher delivers alerts to a notifier in the background, so that a slow
+// or failing receiver never delays ingestion, and records the outcome of
+// every delivery.
+type Dispatcher struct {
+	store    Store
+	notifier Notifier
+	policy   RetryPolicy
+
+	alerts chan delivery
+	// ctx is cancelled by Close to stop waiting between attempts.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type delivery struct {
+	id    string
+	alert *mongo.Alert
+}
+
+func NewDispatcher(store Store, notifier Notifier, policy RetryPolicy) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		store:    store,
+		notifier: notifier,
+		policy:   policy,
+		alerts:   make(chan delivery, policy.QueueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Enqueue queues a stored alert for delivery. It does not block: when the
+// queue is full, the alert is marked undelivered.
+func (d *Dispatcher) Enqueue(id string, alert *mongo.Alert) {
+	select {
+	case d.alerts <- delivery{id: id, alert: alert}:
+	default:
+		log.Printf("Alert delivery queue full, leaving alert %s undelivered", id)
+		d.record(id, errQueueFull)
+	}
+}
+
+// Close stops retrying failed deliveries, tries the queued alerts once and
+// stops the dispatcher. Enqueue must not be called after Close.
+func (d *Dispatcher) Close() {
+	d.cancel()
+	close(d.alerts)
+	<-d.done
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	for delivery := range d.alerts {
+		d.record(delivery.id, d.deliver(delivery))
+	}
+}
+
+// deliver notifies the receiver of an alert, retrying with exponential
+// backoff until it succeeds, the attempts run out or the error is permanent.
+func (d *Dispatcher) deliver(delivery delivery) error {
+	backoff := d.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := d.notifier.Notify(context.Background(), delivery.alert)
+		if err == nil || attempt >= d.policy.Attempts || !retryable(err) {
+			return err
+		}
+		log.Printf("Error delivering alert %s, attempt %d of %d: %v", delivery.id, attempt, d.policy.Attempts, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-d.ctx.Done():
+			return err
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+func (d *Dispatcher) record(id string, deliveryErr error) {
+	if deliveryErr != nil {
+		log.Printf("Error delivering alert %s: %v", id, deliveryErr)
+	}
+	if err := d.store.MarkAlertDelivered(id, deliveryErr); err != nil {
+		log.Printf("Error recording delivery of alert %s: %v", id, err)
+	}
+}
+
+// retryable reports whether a delivery may succeed when retried. Receivers
+// rejecting an alert with a 4xx status will reject it again, unless they
+// timed out or asked to slow down.
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+	switch code := statusErr.StatusCode; {
+	case code == http.StatusRequestTimeout, code == http.StatusTooManyRequests:
+		return true
+	case code >= 400 && code < 500:
+		return false
+	}
+	return true
+}
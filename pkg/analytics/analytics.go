@@ -0,0 +1,95 @@
+package analytics
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+)
+
+const (
+	// DefaultBufferSize is the number of entries queued before new ones are dropped.
+	DefaultBufferSize = 1024
+
+	batchSize     = 100
+	flushInterval = time.Second
+)
+
+// Recorder writes query log entries to MongoDB in the background, so that
+// logging never delays a search. Entries are dropped, and a warning logged,
+// when the database cannot keep up.
+type Recorder struct {
+	db      *mongo.Database
+	entries chan mongo.QueryLog
+	done    chan struct{}
+}
+
+func NewRecorder(db *mongo.Database, bufferSize int) *Recorder {
+	r := &Recorder{
+		db:      db,
+		entries: make(chan mongo.QueryLog, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record queues an entry for writing. It does not block.
+func (r *Recorder) Record(entry mongo.QueryLog) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	select {
+	case r.entries <- entry:
+	default:
+		log.Printf("Query log buffer full, dropping entry for %q", entry.Query)
+	}
+}
+
+// Close writes the queued entries and stops the recorder. Record must not be
+// called after Close.
+func (r *Recorder) Close() {
+	close(r.entries)
+	<-r.done
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]mongo.QueryLog, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.db.StoreQueryLogs(batch); err != nil {
+			log.Printf("Error writing %d query log entries: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry, ok := <-r.entries:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) == batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// NormalizeQuery lower cases a query and collapses its whitespace, so that
+// trivially different spellings of a query are counted together.
+func NormalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
@@ -0,0 +1,163 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bounds the queries accepted by a Schema.
+type Limits struct {
+	// MaxDepth is the deepest nesting of fields allowed.
+	MaxDepth int
+	// MaxCost is the highest cost allowed. Every field costs 1, times the
+	// number of items of the lists it is nested in.
+	MaxCost int
+}
+
+// listSizes is the assumed length of the lists without a first argument,
+// as they bound the resolvers run below them.
+var listSizes = map[string]int{
+	"Query.search":     indexer.DefaultSearchSize,
+	"Patent.inventors": 10,
+}
+
+// checkLimits measures the depth and cost of the operation of a validated
+// document. Introspection fields are not counted, as they are bounded by the
+// size of the schema.
+func (s *Schema) checkLimits(document *ast.Document, operationName string, variables map[string]interface{}) error {
+	m := &measure{
+		schema:    &s.schema,
+		variables: variables,
+		fragments: map[string]*ast.FragmentDefinition{},
+		maxCost:   s.limits.MaxCost,
+	}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		case *ast.FragmentDefinition:
+			m.fragments[definition.Name.Value] = definition
+		}
+	}
+	if operation == nil {
+		return nil
+	}
+	m.selectionSet(operation.SelectionSet, s.schema.QueryType(), 0, 1)
+
+	if m.depth > s.limits.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", m.depth, s.limits.MaxDepth)
+	}
+	if m.cost > s.limits.MaxCost {
+		return fmt.Errorf("query cost exceeds the limit of %d, request fewer fields or items", s.limits.MaxCost)
+	}
+	return nil
+}
+
+type measure struct {
+	schema    *graphql.Schema
+	variables map[string]interface{}
+	fragments map[string]*ast.FragmentDefinition
+	maxCost   int
+
+	depth int
+	cost  int
+}
+
+func (m *measure) selectionSet(set *ast.SelectionSet, parent *graphql.Object, depth, items int) {
+	if set == nil || m.cost > m.maxCost {
+		return
+	}
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			m.field(selection, parent, depth, items)
+		case *ast.InlineFragment:
+			m.selectionSet(selection.SelectionSet, m.condition(selection.TypeCondition, parent), depth, items)
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[selection.Name.Value]; ok {
+				m.selectionSet(fragment.SelectionSet, m.condition(fragment.TypeCondition, parent), depth, items)
+			}
+		}
+	}
+}
+
+func (m *measure) field(field *ast.Field, parent *graphql.Object, depth, items int) {
+	name := field.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return
+	}
+	definition, ok := parent.Fields()[name]
+	if !ok {
+		return
+	}
+	m.depth = max(m.depth, depth+1)
+	m.cost += items
+
+	object, ok := graphql.GetNamed(definition.Type).(*graphql.Object)
+	if !ok {
+		return
+	}
+	m.selectionSet(field.SelectionSet, object, depth+1, items*m.listSize(parent.Name()+"."+name, field, definition))
+}
+
+// listSize returns the number of items a field returns at most: its first
+// argument, the assumed size of a list without one, or 1. Sizes are capped
+// so that the cost cannot overflow.
+func (m *measure) listSize(path string, field *ast.Field, definition *graphql.FieldDefinition) int {
+	for _, argument := range definition.Args {
+		if argument.Name() != "first" {
+			continue
+		}
+		n, ok := argument.DefaultValue.(int)
+		for _, given := range field.Arguments {
+			if given.Name.Value == "first" {
+				if value, found := m.intValue(given.Value); found {
+					n, ok = value, true
+				}
+			}
+		}
+		if ok {
+			return min(max(n, 1), m.maxCost+1)
+		}
+	}
+	if size, ok := listSizes[path]; ok {
+		return size
+	}
+	return 1
+}
+
+func (m *measure) intValue(value ast.Value) (int, bool) {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(value.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch n := m.variables[value.Name.Value].(type) {
+		case int:
+			return n, true
+		case float64:
+			// JSON numbers decode as float64
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+// condition returns the type a fragment applies to.
+func (m *measure) condition(named *ast.Named, parent *graphql.Object) *graphql.Object {
+	if named == nil {
+		return parent
+	}
+	if object, ok := m.schema.Type(named.Name.Value).(*graphql.Object); ok {
+		return object
+	}
+	return parent
+}
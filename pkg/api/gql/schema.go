@@ -0,0 +1,377 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// Bounds of the first argument of list fields.
+const (
+	maxPatentsFirst       = indexer.MaxSearchSize
+	defaultCitationsFirst = 20
+	maxCitationsFirst     = 100
+)
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Schema serves the patent graph: patents with their inventors, assignee,
+// classifications and citations. Patents are read from MongoDB by ID and
+// from the search index otherwise.
+type Schema struct {
+	schema       graphql.Schema
+	db           *mongo.Database
+	searchEngine indexer.SearchBackend
+	limits       Limits
+}
+
+// NewSchema builds the schema, resolving patents with db and searchEngine.
+func NewSchema(db *mongo.Database, searchEngine indexer.SearchBackend, limits Limits) (*Schema, error) {
+	s := &Schema{db: db, searchEngine: searchEngine, limits: limits}
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: s.queryType()})
+	if err != nil {
+		return nil, fmt.Errorf("error building GraphQL schema: %w", err)
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Execute validates a request, rejects it when it is deeper or costlier than
+// the limits allow, and runs it.
+func (s *Schema) Execute(ctx context.Context, req *Request) *graphql.Result {
+	document, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	validation := graphql.ValidateDocument(&s.schema, document, graphql.SpecifiedRules)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+	if err := s.checkLimits(document, req.OperationName, req.Variables); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           document,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+}
+
+// assignee is the value of the Assignee type, which only exists as the name
+// shared by patents.
+type assignee struct {
+	Name string
+}
+
+// field returns a field resolved by get from a source of type T.
+func field[T any](typ graphql.Output, get func(T) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: typ,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(T)), nil
+		},
+	}
+}
+
+func listOf(typ graphql.Type) *graphql.NonNull {
+	return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(typ)))
+}
+
+func firstArgument(defaultValue, max int, items string) graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: defaultValue,
+			Description:  fmt.Sprintf("Number of %s returned, at most %d.", items, max),
+		},
+	}
+}
+
+// first returns the first argument of a field, checked against max.
+func first(p graphql.ResolveParams, max int) (int, error) {
+	n, _ := p.Args["first"].(int)
+	if n < 1 || n > max {
+		return 0, fmt.Errorf("first must be between 1 and %d", max)
+	}
+	return n, nil
+}
+
+func (s *Schema) queryType() *graphql.Object {
+	patent, assigneeType := s.patentTypes()
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"patent": &graphql.Field{
+				Type:        patent,
+				Description: "Patent by storage ID.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _ := p.Args["id"].(string)
+					if _, err := mongo.ParseStorageID(id); err != nil {
+						return nil, errors.New("invalid patent id")
+					}
+					found, err := s.db.RetrievePatent(id)
+					if errors.Is(err, mongo.ErrNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					return found, nil
+				},
+			},
+			"patentByNumber": &graphql.Field{
+				Type:        patent,
+				Description: "Indexed patent by patent number.",
+				Args: graphql.FieldConfigArgument{
+					"number": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					number, _ := p.Args["number"].(string)
+					return s.patentByNumber(p.Context, number)
+				},
+			},
+			"search": &graphql.Field{
+				Type:        listOf(patent),
+				Description: "First page of a query string search, as returned by GET /api/v1/search.",
+				Args: graphql.FieldConfigArgument{
+					"query": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					query, _ := p.Args["query"].(string)
+					results, err := s.searchEngine.SearchAndRetrievePatents(p.Context, query)
+					if err != nil {
+						return nil, err
+					}
+					return patentPointers(results), nil
+				},
+			},
+			"assignee": &graphql.Field{
+				Type:        assigneeType,
+				Description: "Assignee by name.",
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					name, _ := p.Args["name"].(string)
+					return assignee{Name: name}, nil
+				},
+			},
+		},
+	})
+}
+
+// patentTypes builds the Patent and Assignee types and the types reachable
+// from them. Fields are thunks, as the types refer to each other.
+func (s *Schema) patentTypes() (*graphql.Object, *graphql.Object) {
+	var patent, assigneeType *graphql.Object
+
+	inventor := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Inventor",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"name":      field(graphql.NewNonNull(graphql.String), func(i mongo.Inventor) interface{} { return inventorName(i) }),
+				"firstName": field(graphql.String, func(i mongo.Inventor) interface{} { return i.FirstName }),
+				"lastName":  field(graphql.String, func(i mongo.Inventor) interface{} { return i.LastName }),
+				"patents": &graphql.Field{
+					Type:        listOf(patent),
+					Description: "Indexed patents listing an inventor of the same name.",
+					Args:        firstArgument(indexer.DefaultSearchSize, maxPatentsFirst, "patents"),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return s.patentsWithPhrase(p, "InventorNames", inventorName(p.Source.(mongo.Inventor)))
+					},
+				},
+			}
+		}),
+	})
+
+	assigneeType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Assignee",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"name": field(graphql.NewNonNull(graphql.String), func(a assignee) interface{} { return a.Name }),
+				"patents": &graphql.Field{
+					Type:        listOf(patent),
+					Description: "Indexed patents whose assignee name contains this name.",
+					Args:        firstArgument(indexer.DefaultSearchSize, maxPatentsFirst, "patents"),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return s.patentsWithPhrase(p, "AssigneeName", p.Source.(assignee).Name)
+					},
+				},
+			}
+		}),
+	})
+
+	classification := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Classification",
+		Description: "CPC classification.",
+		Fields: graphql.Fields{
+			"symbol":    field(graphql.NewNonNull(graphql.String), func(c mongo.Classification) interface{} { return c.Symbol() }),
+			"section":   field(graphql.String, func(c mongo.Classification) interface{} { return c.Section }),
+			"class":     field(graphql.String, func(c mongo.Classification) interface{} { return c.Class }),
+			"subclass":  field(graphql.String, func(c mongo.Classification) interface{} { return c.Subclass }),
+			"mainGroup": field(graphql.String, func(c mongo.Classification) interface{} { return c.MainGroup }),
+			"subgroup":  field(graphql.String, func(c mongo.Classification) interface{} { return c.Subgroup }),
+			"main":      field(graphql.NewNonNull(graphql.Boolean), func(c mongo.Classification) interface{} { return c.Main }),
+		},
+	})
+
+	citation := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Citation",
+		Description: "Patent cited by a patent.",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"number":   field(graphql.NewNonNull(graphql.String), func(c mongo.Citation) interface{} { return c.DocNumber }),
+				"country":  field(graphql.String, func(c mongo.Citation) interface{} { return c.Country }),
+				"kind":     field(graphql.String, func(c mongo.Citation) interface{} { return c.Kind }),
+				"name":     field(graphql.String, func(c mongo.Citation) interface{} { return c.Name }),
+				"date":     field(graphql.String, func(c mongo.Citation) interface{} { return c.Date }),
+				"category": field(graphql.String, func(c mongo.Citation) interface{} { return c.Category }),
+				"patent": &graphql.Field{
+					Type:        patent,
+					Description: "Cited patent, when it is indexed.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						cited := p.Source.(mongo.Citation)
+						if cited.Country != "" && cited.Country != "US" {
+							return nil, nil
+						}
+						return s.patentByNumber(p.Context, cited.DocNumber)
+					},
+				},
+			}
+		}),
+	})
+
+	patent = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Patent",
+		Fields: (graphql.FieldsThunk)(func() graphql.Fields {
+			return graphql.Fields{
+				"id":              field(graphql.NewNonNull(graphql.ID), func(p *mongo.Patent) interface{} { return p.PatentStorageID }),
+				"number":          field(graphql.String, func(p *mongo.Patent) interface{} { return p.PatentNumber }),
+				"title":           field(graphql.String, func(p *mongo.Patent) interface{} { return p.PatentTitle }),
+				"applicationDate": field(graphql.String, func(p *mongo.Patent) interface{} { return p.ApplicationDate }),
+				"issueDate":       field(graphql.String, func(p *mongo.Patent) interface{} { return p.IssueDate }),
+				"designClass":     field(graphql.String, func(p *mongo.Patent) interface{} { return p.DesignClass }),
+				"inventors":       field(listOf(inventor), func(p *mongo.Patent) interface{} { return p.Inventors }),
+				"assignee": field(assigneeType, func(p *mongo.Patent) interface{} {
+					if p.AssigneeName == "" {
+						return nil
+					}
+					return assignee{Name: p.AssigneeName}
+				}),
+				"classifications": field(listOf(classification), func(p *mongo.Patent) interface{} { return p.Classifications }),
+				"citations": &graphql.Field{
+					Type:        listOf(citation),
+					Description: "Patents cited by the patent. Non-patent literature is left out.",
+					Args:        firstArgument(defaultCitationsFirst, maxCitationsFirst, "citations"),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						n, err := first(p, maxCitationsFirst)
+						if err != nil {
+							return nil, err
+						}
+						citations := p.Source.(*mongo.Patent).Citations
+						return citations[:min(n, len(citations))], nil
+					},
+				},
+			}
+		}),
+	})
+	return patent, assigneeType
+}
+
+func inventorName(i mongo.Inventor) string {
+	return strings.TrimSpace(i.FirstName + " " + i.LastName)
+}
+
+// patentsWithPhrase lists the indexed patents whose field contains text as a
+// phrase.
+func (s *Schema) patentsWithPhrase(p graphql.ResolveParams, field, text string) (interface{}, error) {
+	n, err := first(p, maxPatentsFirst)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.searchEngine.Search(p.Context, &indexer.SearchRequest{
+		Query: &indexer.Clause{Phrase: &indexer.PhraseClause{Field: field, Text: text}},
+		Size:  n,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patentPointers(results.Hits), nil
+}
+
+// patentByNumber returns the indexed patent with a patent number, or nil.
+// Numbers are compared without the zero padding that grants and citations
+// do not use alike, so that "D0567890" finds "D567890".
+func (s *Schema) patentByNumber(ctx context.Context, number string) (interface{}, error) {
+	want := normalizeNumber(number)
+	if want == "" {
+		return nil, nil
+	}
+	results, err := s.searchEngine.Search(ctx, &indexer.SearchRequest{
+		Query: &indexer.Clause{Bool: &indexer.BoolClause{Should: []indexer.Clause{
+			{Match: &indexer.MatchClause{Field: "PatentNumber", Text: number}},
+			{Match: &indexer.MatchClause{Field: "PatentNumber", Text: padNumber(number)}},
+		}}},
+		Size: 5,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range results.Hits {
+		if normalizeNumber(results.Hits[i].PatentNumber) == want {
+			return &results.Hits[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// splitNumber splits a patent number into its letter prefix, such as "D"
+// or "RE", and its digits.
+func splitNumber(number string) (string, string) {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	i := strings.IndexFunc(number, func(r rune) bool { return r >= '0' && r <= '9' })
+	if i < 0 {
+		return number, ""
+	}
+	return number[:i], number[i:]
+}
+
+func normalizeNumber(number string) string {
+	prefix, digits := splitNumber(number)
+	return prefix + strings.TrimLeft(digits, "0")
+}
+
+// padNumber pads a patent number to the eight characters of grant numbers.
+func padNumber(number string) string {
+	prefix, digits := splitNumber(number)
+	digits = strings.TrimLeft(digits, "0")
+	if pad := 8 - len(prefix) - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	return prefix + digits
+}
+
+func patentPointers(patents []mongo.Patent) []*mongo.Patent {
+	pointers := make([]*mongo.Patent, len(patents))
+	for i := range patents {
+		pointers[i] = &patents[i]
+	}
+	return pointers
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/avyukth/search-app/pkg/snapshot"
+	"github.com/gofiber/fiber/v2"
+)
+
+type analyzeRequest struct {
+	Analyzer string `json:"analyzer"`
+	Text     string `json:"text" validate:"required"`
+}
+
+// AnalyzeHandler shows the tokens an index analyzer produces for sample text
+//
+// @Summary Analyze sample text
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body analyzeRequest true "Analyzer and text"
+// @Success 200 {object} analyzeResponse
+// @Failure 400,401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/analyze [post]
+func AnalyzeHandler(searchEngine indexer.SearchBackend) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req analyzeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return problem.New(fiber.StatusBadRequest, "invalid request body")
+		}
+		if req.Text == "" {
+			return problem.New(fiber.StatusBadRequest, "text is required")
+		}
+
+		tokens, err := searchEngine.Analyze(req.Analyzer, req.Text)
+		if err != nil {
+			return err
+		}
+		return c.JSON(analyzeResponse{Tokens: tokens})
+	}
+}
+
+// ReloadSynonymsHandler re-reads the synonym file used by the patent analyzer
+//
+// @Summary Reload the synonym file
+// @Tags admin
+// @Produce json
+// @Success 200 {object} synonymsResponse
+// @Failure 401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/synonyms/reload [post]
+func ReloadSynonymsHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		terms, err := indexer.Synonyms.Reload()
+		if err != nil {
+			return err
+		}
+		return c.JSON(synonymsResponse{Terms: terms})
+	}
+}
+
+// CreateSnapshotHandler writes a point-in-time snapshot of the index
+//
+// @Summary Snapshot the index
+// @Tags admin
+// @Produce json
+// @Success 201 {object} snapshot.Manifest
+// @Failure 401,403,501 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/snapshots [post]
+func CreateSnapshotHandler(snapshots *snapshot.Manager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		manifest, err := snapshots.Create()
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusCreated).JSON(manifest)
+	}
+}
+
+// ListSnapshotsHandler lists the snapshots available for restore, newest first
+//
+// @Summary List index snapshots
+// @Tags admin
+// @Produce json
+// @Success 200 {object} snapshotsResponse
+// @Failure 401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/snapshots [get]
+func ListSnapshotsHandler(snapshots *snapshot.Manager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		manifests, err := snapshots.List()
+		if err != nil {
+			return err
+		}
+		return c.JSON(snapshotsResponse{Snapshots: manifests})
+	}
+}
+
+// RestoreSnapshotHandler replaces the index with a snapshot
+//
+// @Summary Restore the index from a snapshot
+// @Tags admin
+// @Produce json
+// @Param name path string true "Snapshot name"
+// @Success 200 {object} snapshot.Manifest
+// @Failure 400,401,403,404,409,501 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/snapshots/{name}/restore [post]
+func RestoreSnapshotHandler(snapshots *snapshot.Manager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		manifest, err := snapshots.Restore(c.Params("name"))
+		if err != nil {
+			return err
+		}
+		return c.JSON(manifest)
+	}
+}
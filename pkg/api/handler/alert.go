@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/auth"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type savedSearchRequest struct {
+	Name    string            `json:"name" validate:"required"`
+	Query   string            `json:"query"`
+	Filters map[string]string `json:"filters"`
+}
+
+func (r *savedSearchRequest) validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if r.Query == "" && len(r.Filters) == 0 {
+		return errors.New("query or filters are required")
+	}
+	if r.Query != "" {
+		if err := indexer.ValidateQueryString(r.Query); err != nil {
+			return err
+		}
+	}
+	for field := range r.Filters {
+		if !indexer.IsSearchableField(field) {
+			return fmt.Errorf("unknown filter field %q, expected one of %v", field, indexer.SearchableFields)
+		}
+	}
+	return nil
+}
+
+// savedSearchOwner returns the hex ID of the caller's API key, or an empty
+// string when authentication is disabled.
+func savedSearchOwner(c *fiber.Ctx) string {
+	if apiKey := auth.KeyFrom(c); apiKey != nil {
+		return apiKey.ID.Hex()
+	}
+	return ""
+}
+
+// CreateSavedSearchHandler stores a named query that is evaluated against every newly indexed patent
+//
+// @Summary Save a search
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param request body savedSearchRequest true "Saved search"
+// @Success 201 {object} mongo.SavedSearch
+// @Failure 400,401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /saved-searches [post]
+func CreateSavedSearchHandler(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req savedSearchRequest
+		if err := c.BodyParser(&req); err != nil {
+			return problem.New(fiber.StatusBadRequest, "invalid request body")
+		}
+		if err := req.validate(); err != nil {
+			var verr *indexer.ValidationError
+			if errors.As(err, &verr) {
+				return err
+			}
+			return problem.New(fiber.StatusBadRequest, err.Error())
+		}
+
+		search := &mongo.SavedSearch{
+			APIKeyID: savedSearchOwner(c),
+			Name:     req.Name,
+			Query:    req.Query,
+			Filters:  req.Filters,
+		}
+		if _, err := db.StoreSavedSearch(search); err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusCreated).JSON(search)
+	}
+}
+
+// ListSavedSearchesHandler lists the saved searches of the caller
+//
+// @Summary List saved searches
+// @Tags alerts
+// @Produce json
+// @Success 200 {array} mongo.SavedSearch
+// @Failure 401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /saved-searches [get]
+func ListSavedSearchesHandler(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		searches, err := db.ListSavedSearches(savedSearchOwner(c))
+		if err != nil {
+			return err
+		}
+		return c.JSON(searches)
+	}
+}
+
+// DeleteSavedSearchHandler deletes a saved search of the caller
+//
+// @Summary Delete a saved search
+// @Tags alerts
+// @Param id path string true "Saved search ID"
+// @Success 204
+// @Failure 400,401,403,404 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /saved-searches/{id} [delete]
+func DeleteSavedSearchHandler(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		if !primitive.IsValidObjectID(id) {
+			return problem.New(fiber.StatusBadRequest, "invalid saved search id")
+		}
+
+		err := db.DeleteSavedSearch(savedSearchOwner(c), id)
+		if err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// ListAlertsHandler lists the alerts of the caller, optionally for a single saved search
+//
+// @Summary List alerts
+// @Tags alerts
+// @Produce json
+// @Param savedSearchId query string false "Saved search ID"
+// @Param limit query int false "Number of alerts" minimum(1) maximum(500) default(50)
+// @Success 200 {array} mongo.Alert
+// @Failure 400,401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /alerts [get]
+func ListAlertsHandler(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 50)
+		if limit < 1 || limit > 500 {
+			return problem.New(fiber.StatusBadRequest, "limit must be between 1 and 500")
+		}
+
+		savedSearchID := c.Query("savedSearchId")
+		if savedSearchID != "" && !primitive.IsValidObjectID(savedSearchID) {
+			return problem.New(fiber.StatusBadRequest, "invalid saved search id")
+		}
+
+		alerts, err := db.ListAlerts(savedSearchOwner(c), savedSearchID, int64(limit))
+		if err != nil {
+			return err
+		}
+		return c.JSON(alerts)
+	}
+}
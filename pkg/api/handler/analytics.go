@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultAnalyticsWindow = 24 * time.Hour
+	maxAnalyticsWindow     = 90 * 24 * time.Hour
+	defaultAnalyticsLimit  = 20
+	maxAnalyticsLimit      = 100
+)
+
+// latencyPercentiles are the percentiles reported by SearchLatencyHandler.
+var latencyPercentiles = []float64{50, 90, 95, 99}
+
+// analyticsWindow parses the window query parameter, a duration such as 1h or 168h
+func analyticsWindow(c *fiber.Ctx) (time.Time, error) {
+	window := defaultAnalyticsWindow
+	if value := c.Query("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 || parsed > maxAnalyticsWindow {
+			return time.Time{}, errors.New("window must be a positive duration of at most 2160h")
+		}
+		window = parsed
+	}
+	return time.Now().Add(-window), nil
+}
+
+func analyticsLimit(c *fiber.Ctx) (int64, error) {
+	limit := c.QueryInt("limit", defaultAnalyticsLimit)
+	if limit < 1 || limit > maxAnalyticsLimit {
+		return 0, errors.New("limit must be between 1 and 100")
+	}
+	return int64(limit), nil
+}
+
+// TopQueriesHandler reports the most frequent search queries over a time window
+//
+// @Summary Report the most frequent queries
+// @Tags analytics
+// @Produce json
+// @Param window query string false "Duration to report on, such as 1h or 168h" default(24h)
+// @Param limit query int false "Number of queries" minimum(1) maximum(100) default(20)
+// @Success 200 {object} queryCountResponse
+// @Failure 400,401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /analytics/top-queries [get]
+func TopQueriesHandler(db *mongo.Database) fiber.Handler {
+	return queryCountHandler(db.TopQueries)
+}
+
+// ZeroResultQueriesHandler reports the most frequent search queries that matched nothing over a time window
+//
+// @Summary Report the most frequent queries that matched nothing
+// @Tags analytics
+// @Produce json
+// @Param window query string false "Duration to report on, such as 1h or 168h" default(24h)
+// @Param limit query int false "Number of queries" minimum(1) maximum(100) default(20)
+// @Success 200 {object} queryCountResponse
+// @Failure 400,401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /analytics/zero-results [get]
+func ZeroResultQueriesHandler(db *mongo.Database) fiber.Handler {
+	return queryCountHandler(db.ZeroResultQueries)
+}
+
+func queryCountHandler(count func(since time.Time, limit int64) ([]mongo.QueryCount, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		since, err := analyticsWindow(c)
+		if err != nil {
+			return problem.New(fiber.StatusBadRequest, err.Error())
+		}
+		limit, err := analyticsLimit(c)
+		if err != nil {
+			return problem.New(fiber.StatusBadRequest, err.Error())
+		}
+
+		queries, err := count(since, limit)
+		if err != nil {
+			return err
+		}
+		return c.JSON(queryCountResponse{Since: since, Queries: queries})
+	}
+}
+
+// SearchLatencyHandler reports search latency percentiles over a time window
+//
+// @Summary Report search latency percentiles
+// @Tags analytics
+// @Produce json
+// @Param window query string false "Duration to report on, such as 1h or 168h" default(24h)
+// @Success 200 {object} latencyResponse
+// @Failure 400,401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /analytics/latency [get]
+func SearchLatencyHandler(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		since, err := analyticsWindow(c)
+		if err != nil {
+			return problem.New(fiber.StatusBadRequest, err.Error())
+		}
+
+		report, err := db.SearchLatency(since, latencyPercentiles)
+		if err != nil {
+			return err
+		}
+		return c.JSON(latencyResponse{Since: since, Count: report.Count, LatencyMS: report.Percentiles})
+	}
+}
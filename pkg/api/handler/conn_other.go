@@ -0,0 +1,11 @@
+//go:build !unix
+
+package handler
+
+import "syscall"
+
+// connClosed is only implemented on Unix systems; elsewhere requests are not
+// cancelled when the client goes away.
+func connClosed(conn syscall.Conn) bool {
+	return false
+}
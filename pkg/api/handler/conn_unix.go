@@ -0,0 +1,23 @@
+//go:build unix
+
+package handler
+
+import "syscall"
+
+// connClosed reports whether the client closed conn. It peeks at the socket,
+// so that what the client sent is left for the server to read.
+func connClosed(conn syscall.Conn) bool {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return false
+	}
+	closed := false
+	var buf [1]byte
+	raw.Control(func(fd uintptr) {
+		// Sockets are non-blocking, so this fails with EAGAIN while the
+		// client is connected and has nothing more to send.
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK)
+		closed = (n == 0 && err == nil) || err == syscall.ECONNRESET
+	})
+	return closed
+}
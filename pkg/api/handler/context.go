@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// closePollInterval is how often the connection of a running request is
+// checked for a client that went away.
+const closePollInterval = 100 * time.Millisecond
+
+// RequestContext sets the context returned by c.UserContext to one cancelled
+// when the client closes its connection and once the handler returns, so that
+// searches stop with the request that started them. Responses streamed after
+// the handler returns need their own context.
+func RequestContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		if conn, ok := c.Context().Conn().(syscall.Conn); ok {
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				watchClose(ctx, conn, cancel)
+			}()
+			defer func() {
+				cancel()
+				<-stopped
+			}()
+		}
+		return c.Next()
+	}
+}
+
+// watchClose calls cancel when the client closes conn, until ctx is done.
+func watchClose(ctx context.Context, conn syscall.Conn, cancel context.CancelFunc) {
+	ticker := time.NewTicker(closePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if connClosed(conn) {
+				cancel()
+				return
+			}
+		}
+	}
+}
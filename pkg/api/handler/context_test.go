@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// serve runs app on a local port and returns its address.
+func serve(t *testing.T, app *fiber.App) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	return ln.Addr().String()
+}
+
+func TestRequestContext(t *testing.T) {
+	done := make(chan error, 1)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(RequestContext())
+	app.Get("/wait", func(c *fiber.Ctx) error {
+		select {
+		case <-c.UserContext().Done():
+			done <- c.UserContext().Err()
+		case <-time.After(5 * time.Second):
+			done <- errors.New("context not cancelled")
+		}
+		return nil
+	})
+	app.Get("/quick", func(c *fiber.Ctx) error {
+		if err := c.UserContext().Err(); err != nil {
+			return err
+		}
+		return c.SendString("ok")
+	})
+	addr := serve(t, app)
+
+	// A client that goes away cancels its request.
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(conn, "GET /wait HTTP/1.1\r\nHost: %s\r\n\r\n", addr)
+	time.Sleep(2 * closePollInterval)
+	conn.Close()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("request context error = %v, want %v", err, context.Canceled)
+	}
+
+	// Requests of a connected client run to completion, and the connection
+	// can be reused.
+	client := &http.Client{Timeout: 5 * time.Second}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get("http://" + addr + "/quick")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+	}
+	client.CloseIdleConnections()
+}
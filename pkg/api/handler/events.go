@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/events"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// eventHeartbeat is how often an idle event stream is probed, so that
+	// closed connections are noticed and proxies keep the stream open.
+	eventHeartbeat = 15 * time.Second
+	// eventWriteTimeout bounds each write to an event stream. It replaces the
+	// server write timeout, which would otherwise end long streams.
+	eventWriteTimeout = 10 * time.Second
+)
+
+// JobEventsHandler streams the state changes and file counters of an ingestion
+// job until it finishes, as Server-Sent Events or, for WebSocket upgrade
+// requests, as JSON messages. The first event is the current state of the job.
+//
+// @Summary Stream the events of an ingestion job
+// @Description Sent as Server-Sent Events, or as JSON messages after a WebSocket upgrade.
+// @Tags jobs
+// @Produce text/event-stream
+// @Param id path string true "Job ID"
+// @Success 200 {object} events.Event
+// @Failure 400,401,403,404 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /jobs/{id}/events [get]
+func JobEventsHandler(db *mongo.Database, broker *events.Broker) fiber.Handler {
+	upgrade := websocket.New(func(conn *websocket.Conn) {
+		streamJobWebSocket(conn, db, broker)
+	})
+
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		if !primitive.IsValidObjectID(id) {
+			return problem.New(fiber.StatusBadRequest, "invalid job id")
+		}
+		if websocket.IsWebSocketUpgrade(c) {
+			if _, err := db.RetrieveJob(id); err != nil {
+				return err
+			}
+			return upgrade(c)
+		}
+
+		first, stream, cancel, err := broker.Watch(db, id)
+		if err != nil {
+			return err
+		}
+
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Set("X-Accel-Buffering", "no")
+
+		conn := c.Context().Conn()
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+			write := func(payload string) error {
+				conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+				if _, err := w.WriteString(payload); err != nil {
+					return err
+				}
+				return w.Flush()
+			}
+			writeEvent := func(event events.Event) error {
+				data, err := json.Marshal(event)
+				if err != nil {
+					return err
+				}
+				return write(fmt.Sprintf("event: %s\ndata: %s\n\n", event.Type, data))
+			}
+
+			if err := writeEvent(first); err != nil || first.Final() {
+				return
+			}
+			heartbeat := time.NewTicker(eventHeartbeat)
+			defer heartbeat.Stop()
+			for {
+				select {
+				case event, ok := <-stream:
+					if !ok {
+						return
+					}
+					if err := writeEvent(event); err != nil || event.Final() {
+						return
+					}
+				case <-heartbeat.C:
+					if err := write(": keep-alive\n\n"); err != nil {
+						return
+					}
+				}
+			}
+		})
+		return nil
+	}
+}
+
+func streamJobWebSocket(conn *websocket.Conn, db *mongo.Database, broker *events.Broker) {
+	first, stream, cancel, err := broker.Watch(db, conn.Params("id"))
+	if err != nil {
+		closeWebSocket(conn, websocket.CloseInternalServerErr, err.Error())
+		return
+	}
+	defer cancel()
+
+	// Read until the client goes away, so that its close frame is handled.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	writeEvent := func(event events.Event) error {
+		conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		return conn.WriteJSON(event)
+	}
+
+	if err := writeEvent(first); err != nil {
+		return
+	}
+	if first.Final() {
+		closeWebSocket(conn, websocket.CloseNormalClosure, "job finished")
+		return
+	}
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				closeWebSocket(conn, websocket.CloseNormalClosure, "")
+				return
+			}
+			if err := writeEvent(event); err != nil {
+				return
+			}
+			if event.Final() {
+				closeWebSocket(conn, websocket.CloseNormalClosure, "job finished")
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventWriteTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(eventWriteTimeout)); err != nil {
+		log.Printf("Error closing job event WebSocket: %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// exportWriteTimeout bounds each write of an export. It replaces the server
+// write timeout, which would otherwise end large exports.
+const exportWriteTimeout = 30 * time.Second
+
+// exportColumn is a mongo.Patent field that can be exported.
+type exportColumn struct {
+	name  string
+	value func(*mongo.Patent) interface{}
+}
+
+// exportColumns lists the exportable fields in their default order.
+var exportColumns = []exportColumn{
+	{"PatentNumber", func(p *mongo.Patent) interface{} { return p.PatentNumber }},
+	{"PatentTitle", func(p *mongo.Patent) interface{} { return p.PatentTitle }},
+	{"InventorNames", func(p *mongo.Patent) interface{} { return p.InventorNames }},
+	{"AssigneeName", func(p *mongo.Patent) interface{} { return p.AssigneeName }},
+	{"ApplicationDate", func(p *mongo.Patent) interface{} { return p.ApplicationDate }},
+	{"IssueDate", func(p *mongo.Patent) interface{} { return p.IssueDate }},
+	{"DesignClass", func(p *mongo.Patent) interface{} { return p.DesignClass }},
+	{"PatentStorageID", func(p *mongo.Patent) interface{} { return p.PatentStorageID }},
+}
+
+// parseExportColumns resolves a comma-separated list of column names. An
+// empty list selects every column.
+func parseExportColumns(list string) ([]exportColumn, error) {
+	if list == "" {
+		return exportColumns, nil
+	}
+	var columns []exportColumn
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, column := range exportColumns {
+			if column.name == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, 0, len(exportColumns))
+			for _, column := range exportColumns {
+				names = append(names, column.name)
+			}
+			return nil, fmt.Errorf("unknown column %q, expected one of %v", name, names)
+		}
+	}
+	return columns, nil
+}
+
+// exportText renders a column value as a single spreadsheet cell.
+func exportText(value interface{}) string {
+	if values, ok := value.([]string); ok {
+		return strings.Join(values, "; ")
+	}
+	return fmt.Sprint(value)
+}
+
+// exportWriter encodes exported patents in one format.
+type exportWriter interface {
+	WriteRow(patent *mongo.Patent) error
+	// Close writes whatever the format still buffers.
+	Close() error
+	// Abort releases the writer without finishing the output.
+	Abort()
+}
+
+type exportFormat struct {
+	contentType string
+	extension   string
+	newWriter   func(w io.Writer, columns []exportColumn) (exportWriter, error)
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":   {"text/csv; charset=utf-8", "csv", newCSVExport},
+	"jsonl": {"application/x-ndjson", "jsonl", newJSONLExport},
+	"xlsx":  {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx", newXLSXExport},
+}
+
+type csvExport struct {
+	w       *csv.Writer
+	columns []exportColumn
+	record  []string
+}
+
+func newCSVExport(w io.Writer, columns []exportColumn) (exportWriter, error) {
+	e := &csvExport{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		e.record[i] = column.name
+	}
+	return e, e.w.Write(e.record)
+}
+
+func (e *csvExport) WriteRow(patent *mongo.Patent) error {
+	for i, column := range e.columns {
+		e.record[i] = exportText(column.value(patent))
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvExport) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExport) Abort() {}
+
+type jsonlExport struct {
+	w       io.Writer
+	columns []exportColumn
+	line    bytes.Buffer
+}
+
+func newJSONLExport(w io.Writer, columns []exportColumn) (exportWriter, error) {
+	return &jsonlExport{w: w, columns: columns}, nil
+}
+
+// WriteRow writes an object with the selected columns, in their order.
+func (e *jsonlExport) WriteRow(patent *mongo.Patent) error {
+	e.line.Reset()
+	e.line.WriteByte('{')
+	for i, column := range e.columns {
+		if i > 0 {
+			e.line.WriteByte(',')
+		}
+		value, err := json.Marshal(column.value(patent))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&e.line, "%q:", column.name)
+		e.line.Write(value)
+	}
+	e.line.WriteString("}\n")
+	_, err := e.w.Write(e.line.Bytes())
+	return err
+}
+
+func (e *jsonlExport) Close() error {
+	return nil
+}
+
+func (e *jsonlExport) Abort() {}
+
+// errSheetFull is returned once a worksheet holds as many rows as XLSX allows.
+var errSheetFull = errors.New("export exceeds the XLSX row limit")
+
+// xlsxExport writes rows through an excelize stream writer, which keeps them
+// in a temporary file rather than in memory. The workbook is only written out
+// on Close, as the XLSX container cannot be produced before its last row.
+type xlsxExport struct {
+	w       io.Writer
+	file    *excelize.File
+	stream  *excelize.StreamWriter
+	columns []exportColumn
+	row     int
+}
+
+func newXLSXExport(w io.Writer, columns []exportColumn) (exportWriter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	e := &xlsxExport{w: w, file: file, stream: stream, columns: columns, row: 1}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	if err := stream.SetRow("A1", header, excelize.RowOpts{}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *xlsxExport) WriteRow(patent *mongo.Patent) error {
+	if e.row >= excelize.TotalRows {
+		return errSheetFull
+	}
+	e.row++
+	values := make([]interface{}, len(e.columns))
+	for i, column := range e.columns {
+		values[i] = exportText(column.value(patent))
+	}
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(cell, values)
+}
+
+func (e *xlsxExport) Close() error {
+	defer e.file.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
+
+// Abort removes the staged rows without writing the workbook.
+func (e *xlsxExport) Abort() {
+	e.file.Close()
+}
+
+// deadlineWriter extends the write deadline of conn before every write, so
+// that a stream is only cut off when the client stalls.
+type deadlineWriter struct {
+	conn    net.Conn
+	w       io.Writer
+	timeout time.Duration
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	d.conn.SetWriteDeadline(time.Now().Add(d.timeout))
+	return d.w.Write(p)
+}
+
+// writeExport writes first and the patents following it in cursor to out, and
+// finishes the output. On a failure, the output is abandoned unfinished and
+// the error returned. A full XLSX sheet ends the export early.
+func writeExport(out exportWriter, first *mongo.Patent, cursor indexer.PatentCursor) error {
+	for patent := first; patent != nil; {
+		err := out.WriteRow(patent)
+		if errors.Is(err, errSheetFull) {
+			log.Printf("Export truncated: %v", err)
+			break
+		}
+		if err != nil {
+			out.Abort()
+			return fmt.Errorf("error writing export: %w", err)
+		}
+		patent, err = cursor.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			out.Abort()
+			return fmt.Errorf("error exporting search results: %w", err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("error finishing export: %w", err)
+	}
+	return nil
+}
+
+// ExportHandler runs the same query string search as SearchHandler and
+// streams every match, not just the first page, as CSV, JSON Lines or XLSX.
+// The columns parameter selects and orders the exported patent fields.
+//
+// @Summary Export every match of a query string search
+// @Tags search
+// @Produce text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param query query string true "Query string"
+// @Param format query string false "Export format" Enums(csv, jsonl, xlsx) default(csv)
+// @Param columns query string false "Comma-separated patent fields to export, in order; all by default"
+// @Success 200 {file} file
+// @Failure 400,401,403,429,503 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /search/export [get]
+func ExportHandler(searchEngine indexer.SearchBackend) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query := c.Query("query")
+		if query == "" {
+			return problem.New(fiber.StatusBadRequest, "query is required")
+		}
+		formatName := c.Query("format", "csv")
+		format, ok := exportFormats[formatName]
+		if !ok {
+			return problem.Newf(fiber.StatusBadRequest, "unknown format %q, expected csv, jsonl or xlsx", formatName)
+		}
+		columns, err := parseExportColumns(c.Query("columns"))
+		if err != nil {
+			return problem.New(fiber.StatusBadRequest, err.Error())
+		}
+
+		// The response is streamed after the handler returns, when the
+		// request context is cancelled, so the export has its own context,
+		// cancelled once the stream ends. A client going away ends the
+		// stream with a write error.
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// The first page is fetched before the response starts, so that
+		// rejected and timed out searches still get an error status.
+		cursor := searchEngine.ExportPatents(ctx, query)
+		first, err := cursor.Next()
+		if err != nil && err != io.EOF {
+			cancel()
+			return err
+		}
+
+		filename := fmt.Sprintf("patents-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format.extension)
+		c.Set(fiber.HeaderContentType, format.contentType)
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+		conn := c.Context().Conn()
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+			out, err := format.newWriter(&deadlineWriter{conn: conn, w: w, timeout: exportWriteTimeout}, columns)
+			if err == nil {
+				err = writeExport(out, first, cursor)
+			}
+			if err != nil {
+				log.Printf("Error streaming %s export: %v", formatName, err)
+				// Closing the connection leaves the chunked body
+				// unterminated, so that the client sees the download
+				// fail instead of a truncated file.
+				conn.Close()
+			}
+		})
+		return nil
+	}
+}
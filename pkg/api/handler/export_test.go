@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/gofiber/fiber/v2"
+)
+
+// exportPage is the number of patents the fake cursor returns before its
+// error, as the index would for a first page.
+const exportPage = 500
+
+// fakeCursor returns rows patents, then err.
+type fakeCursor struct {
+	rows int
+	err  error
+}
+
+func (c *fakeCursor) Next() (*mongo.Patent, error) {
+	if c.rows == 0 {
+		return nil, c.err
+	}
+	c.rows--
+	return &mongo.Patent{PatentNumber: fmt.Sprintf("D%06d", c.rows), PatentTitle: "Chair"}, nil
+}
+
+type exportBackend struct {
+	indexer.SearchBackend
+	err error
+}
+
+func (b *exportBackend) ExportPatents(ctx context.Context, searchTerm string) indexer.PatentCursor {
+	return &fakeCursor{rows: exportPage, err: b.err}
+}
+
+func TestExportHandlerCursorFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		// err is returned by the cursor after the first page.
+		err     error
+		wantErr bool
+	}{
+		{"complete", io.EOF, false},
+		{"failure after the first page", errors.New("search timed out"), true},
+	}
+	for _, format := range []string{"csv", "jsonl", "xlsx"} {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				app := fiber.New(fiber.Config{DisableStartupMessage: true})
+				app.Get("/export", ExportHandler(&exportBackend{err: tt.err}))
+				addr := serve(t, app)
+
+				client := &http.Client{Timeout: 5 * time.Second}
+				defer client.CloseIdleConnections()
+				body, err := get(client, "http://"+addr+"/export?query=chair&format="+format)
+				if tt.wantErr {
+					// The connection is closed before or after the headers,
+					// depending on how much of the export was buffered.
+					if err == nil {
+						t.Fatalf("read a complete %d byte export, want the download to fail", len(body))
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				switch format {
+				case "xlsx":
+					if _, err := zip.NewReader(bytes.NewReader(body), int64(len(body))); err != nil {
+						t.Fatalf("invalid workbook: %v", err)
+					}
+				default:
+					// One line per patent, after the CSV header.
+					lines := bytes.Count(body, []byte("\n"))
+					if format == "csv" {
+						lines--
+					}
+					if lines != exportPage {
+						t.Fatalf("exported %d patents, want %d", lines, exportPage)
+					}
+				}
+			})
+		}
+	}
+}
+
+// get returns the body of a 200 response to a GET of url.
+func get(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
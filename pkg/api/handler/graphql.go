@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/avyukth/search-app/pkg/api/gql"
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GraphQLHandler runs a GraphQL query, sent as a JSON body with POST or as the
+// query, operationName and variables parameters with GET. Query errors, such
+// as exceeding the depth or cost limits, are returned in the errors field of
+// the result.
+//
+// @Summary Run a GraphQL query
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param query query string false "Query, with GET"
+// @Param operationName query string false "Operation to run, with GET"
+// @Param variables query string false "JSON object of variables, with GET"
+// @Param request body gql.Request false "Request, with POST"
+// @Success 200 {object} object
+// @Failure 400,401,403,429 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /graphql [get]
+// @Router /graphql [post]
+func GraphQLHandler(schema *gql.Schema) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req gql.Request
+		if c.Method() == fiber.MethodGet {
+			req.Query = c.Query("query")
+			req.OperationName = c.Query("operationName")
+			if variables := c.Query("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+					return problem.New(fiber.StatusBadRequest, "variables must be a JSON object")
+				}
+			}
+		} else if err := c.BodyParser(&req); err != nil {
+			return problem.New(fiber.StatusBadRequest, "invalid request body")
+		}
+		if req.Query == "" {
+			return problem.New(fiber.StatusBadRequest, "query is required")
+		}
+
+		return c.JSON(schema.Execute(c.UserContext(), &req))
+	}
+}
@@ -1,74 +1,234 @@
 package handler
 
 import (
-	"net/http"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
 
+	"github.com/avyukth/search-app/pkg/analytics"
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/auth"
 	"github.com/avyukth/search-app/pkg/database/mongo"
 	"github.com/avyukth/search-app/pkg/indexer"
-	"github.com/avyukth/search-app/pkg/queue"
+	"github.com/avyukth/search-app/pkg/ingest"
 	"github.com/gofiber/fiber/v2"
 )
 
-// DownloadHandler handles download requests for tar files
-func DownloadHandler(db *mongo.Database, q *queue.TaskQueue) fiber.Handler {
+// DownloadHandler handles download requests for tar files. It is deprecated
+// in favour of CreateIngestionHandler.
+//
+// @Summary Download and ingest a bulk grant archive
+// @Description Replaced by POST /ingestions with type "download".
+// @Tags ingestion
+// @Produce json
+// @Param link query string true "URL of the archive"
+// @Success 202 {object} ingestionResponse
+// @Failure 400,401,403,404,409,429,503 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Deprecated
+// @Router /download [get]
+func DownloadHandler(ingestions *ingest.Submitter) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		link := c.Query("link")
 		if link == "" {
-			return c.Status(fiber.StatusBadRequest).SendString("Link is required")
+			return problem.New(fiber.StatusBadRequest, "Link is required")
 		}
 
-		// Check if the link is live
-		resp, err := http.Head(link)
-		if err != nil || resp.StatusCode != http.StatusOK {
-			return c.Status(fiber.StatusNotFound).SendString("Link is not live")
+		result, err := ingestions.Submit(c.UserContext(), &ingest.Request{Type: ingest.TypeDownload, URL: link}, "", "")
+		if err != nil {
+			return err
 		}
+		return c.Status(fiber.StatusAccepted).JSON(ingestionResponse{Message: "Link is sent for processing", JobID: result.JobID})
+	}
+}
+
+// searchResponse is the body returned by SearchHandler. Spelling is only set
+// when the query matched nothing, and CorrectedQuery when it was rerun with
+// the spelling correction.
+type searchResponse struct {
+	Query          string                  `json:"query"`
+	CorrectedQuery string                  `json:"correctedQuery,omitempty"`
+	Results        []mongo.Patent          `json:"results"`
+	Spelling       *indexer.SpellingResult `json:"spelling,omitempty"`
+}
+
+// SearchHandler runs a query string search, suggesting spelling corrections when nothing matches
+//
+// @Summary Search patents with a query string
+// @Description Queries that match nothing return spelling suggestions, and are rerun with the first suggestion when autocorrect is set.
+// @Tags search
+// @Produce json
+// @Param query query string false "Query string"
+// @Param autocorrect query bool false "Rerun queries that match nothing with the suggested spelling"
+// @Success 200 {object} searchResponse
+// @Failure 400,401,403,429,503 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /search [get]
+func SearchHandler(db *mongo.Database, searchEngine indexer.SearchBackend, queryLog *analytics.Recorder) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		// Extract search parameters from the request
+		query := c.Query("query")
+
+		// Perform search operation using the search engine instance
+		results, err := searchEngine.SearchAndRetrievePatents(c.UserContext(), query)
 
-		// Check and set link status in MongoDB
-		isSet, err := db.CheckAndSetLinkStatus(link)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Error processing link")
+			return err
 		}
-		if !isSet {
-			return c.Status(fiber.StatusConflict).SendString("Link is already processed or completed")
+		response := searchResponse{Query: query, Results: results}
+		if len(results) > 0 {
+			recordSearch(c, queryLog, query, searchFilters(c), len(results), start)
+			return c.JSON(response)
 		}
 
-		task := queue.Task{
-			FilePath: link,
-			Type:     queue.DownloadAndProcess,
+		// Nothing matched, suggest spelling corrections from the index vocabulary
+		response.Spelling, err = searchEngine.SuggestSpelling(query)
+		if err != nil {
+			return err
 		}
-		// Send link to processing queue and return response
-		q.Enqueue(task)
-		return c.SendString("Link is sent for processing")
+		if c.QueryBool("autocorrect") && response.Spelling.DidYouMean != "" {
+			response.CorrectedQuery = response.Spelling.DidYouMean
+			response.Results, err = searchEngine.SearchAndRetrievePatents(c.UserContext(), response.CorrectedQuery)
+			if err != nil {
+				return err
+			}
+		}
+		// Hits are those of the query as typed, so that corrected queries
+		// still show up in the zero-result report.
+		recordSearch(c, queryLog, query, searchFilters(c), len(results), start)
+		return c.JSON(response)
 	}
 }
 
-func SearchHandler(db *mongo.Database, searchEngine *indexer.SearchEngine) fiber.Handler {
+// searchFilters returns the query parameters of a search request other than the query itself
+func searchFilters(c *fiber.Ctx) map[string]string {
+	var filters map[string]string
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if string(key) == "query" {
+			return
+		}
+		if filters == nil {
+			filters = map[string]string{}
+		}
+		filters[string(key)] = string(value)
+	})
+	return filters
+}
+
+// recordSearch adds a completed search to the query log. The caller is the
+// ID of the API key, or the client IP when authentication is disabled.
+func recordSearch(c *fiber.Ctx, queryLog *analytics.Recorder, query string, filters map[string]string, hits int, start time.Time) {
+	caller := c.IP()
+	if apiKey := auth.KeyFrom(c); apiKey != nil {
+		caller = apiKey.ID.Hex()
+	}
+	queryLog.Record(mongo.QueryLog{
+		Endpoint:  c.Method() + " " + c.Route().Path,
+		Query:     analytics.NormalizeQuery(query),
+		Filters:   filters,
+		Hits:      hits,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Caller:    caller,
+	})
+}
+
+// AdvancedSearchHandler runs a JSON query DSL request against the search engine
+//
+// @Summary Search patents with the query DSL
+// @Tags search
+// @Accept json
+// @Produce json
+// @Param request body indexer.SearchRequest true "Search request"
+// @Success 200 {object} indexer.SearchResult
+// @Failure 400,401,403,429,503 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /search [post]
+func AdvancedSearchHandler(searchEngine indexer.SearchBackend, queryLog *analytics.Recorder) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Extract search parameters from the request
-		query := c.Query("query")
+		start := time.Now()
+		var req indexer.SearchRequest
+		decoder := json.NewDecoder(bytes.NewReader(c.Body()))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			return problem.New(fiber.StatusBadRequest, "invalid request body: "+err.Error())
+		}
 
-		// Perform search operation using the search engine instance
-		results, err := searchEngine.SearchAndRetrievePatents(query)
+		if err := req.Validate(); err != nil {
+			var verr *indexer.ValidationError
+			if errors.As(err, &verr) {
+				return err
+			}
+			return problem.New(fiber.StatusBadRequest, err.Error())
+		}
 
+		results, err := searchEngine.Search(c.UserContext(), &req)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			return err
 		}
+		// The query clause is logged as JSON; encoding/json sorts map keys,
+		// so equal clauses are counted together.
+		clause, _ := json.Marshal(req.Query)
+		recordSearch(c, queryLog, string(clause), nil, int(results.Total), start)
 		return c.JSON(results)
 	}
 }
 
-func CrawlerHandler(db *mongo.Database, q *queue.TaskQueue) fiber.Handler {
+// CrawlerHandler walks a directory on the server. It is deprecated in favour
+// of CreateIngestionHandler.
+//
+// @Summary Walk a directory on the server and ingest its files
+// @Description Replaced by POST /ingestions with type "crawl".
+// @Tags ingestion
+// @Produce json
+// @Param path query string true "Directory on the server"
+// @Success 202 {object} ingestionResponse
+// @Failure 400,401,403,429,503 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Deprecated
+// @Router /crawl [get]
+func CrawlerHandler(ingestions *ingest.Submitter) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		dirPath := c.Query("path")
 		if dirPath == "" {
-			return c.Status(fiber.StatusBadRequest).SendString("Path is required")
+			return problem.New(fiber.StatusBadRequest, "Path is required")
 		}
 
-		task := queue.Task{
-			FilePath: dirPath,
-			Type:     queue.WalkAndProcess,
+		result, err := ingestions.Submit(c.UserContext(), &ingest.Request{Type: ingest.TypeCrawl, Path: dirPath}, "", "")
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusAccepted).JSON(ingestionResponse{Message: "Directory is sent for walking and processing", JobID: result.JobID})
+	}
+}
+
+// InventorSearchHandler finds patents by inventor name, tolerating typos, spelling variants and initials
+//
+// @Summary Search patents by inventor name
+// @Tags search
+// @Produce json
+// @Param name query string true "Inventor name, as \"Last, First\" or \"First Last\""
+// @Param size query int false "Number of matches" minimum(1) maximum(100) default(10)
+// @Success 200 {array} indexer.InventorMatch
+// @Failure 400,401,403,429 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /search/inventors [get]
+func InventorSearchHandler(searchEngine indexer.SearchBackend) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Query("name")
+		if name == "" {
+			return problem.New(fiber.StatusBadRequest, "name is required")
+		}
+		size := c.QueryInt("size", indexer.DefaultSearchSize)
+		if size < 1 || size > indexer.MaxSearchSize {
+			return problem.New(fiber.StatusBadRequest, "size must be between 1 and 100")
+		}
+
+		matches, err := searchEngine.SearchInventors(name, size)
+		if err != nil {
+			return err
 		}
-		q.Enqueue(task)
-		return c.SendString("Directory is sent for walking and processing")
+		return c.JSON(matches)
 	}
 }
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"github.com/avyukth/search-app/pkg/health"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LiveHandler reports that the server is up. It is deprecated in favour of
+// HealthzHandler, which also checks the index and the queue workers.
+//
+// @Summary Check that the server is up
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string "OK"
+// @Deprecated
+// @Router /live [get]
+func LiveHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.SendString("OK")
+	}
+}
+
+// HealthzHandler reports whether the server is alive, failing when only a
+// restart can repair it
+//
+// @Summary Check that the server is alive
+// @Description Fails when the index is closed or a queue worker has exited.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /healthz [get]
+func HealthzHandler(checker *health.Checker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return sendReport(c, checker.Live(c.UserContext()))
+	}
+}
+
+// ReadyzHandler reports whether the server can serve requests
+//
+// @Summary Check that the server is ready
+// @Description Fails when MongoDB does not answer a ping, the index is closed, a queue worker has exited or the storage directory is short of disk space.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func ReadyzHandler(checker *health.Checker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return sendReport(c, checker.Ready(c.UserContext()))
+	}
+}
+
+// sendReport sends a health report, with 503 when a check failed.
+func sendReport(c *fiber.Ctx, report *health.Report) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	status := fiber.StatusOK
+	if !report.OK() {
+		status = fiber.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/auth"
+	"github.com/avyukth/search-app/pkg/ingest"
+	"github.com/gofiber/fiber/v2"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// CreateIngestionHandler starts an ingestion job from a JSON request. A
+// request sent again by the same API key with the same Idempotency-Key header
+// returns the job of the first request instead of starting another one.
+//
+// @Summary Start an ingestion job
+// @Tags ingestion
+// @Accept json
+// @Produce json
+// @Param request body ingest.Request true "Ingestion request"
+// @Param Idempotency-Key header string false "Key making retries of the request safe" maxlength(255)
+// @Success 202 {object} ingestionResponse
+// @Failure 400,401,403,404,409,422,429,503 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /ingestions [post]
+func CreateIngestionHandler(ingestions *ingest.Submitter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req ingest.Request
+		decoder := json.NewDecoder(bytes.NewReader(c.Body()))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			return problem.New(fiber.StatusBadRequest, "invalid request body: "+err.Error())
+		}
+		if err := req.Validate(); err != nil {
+			return problem.New(fiber.StatusBadRequest, err.Error())
+		}
+
+		key := c.Get(idempotencyKeyHeader)
+		if len(key) > ingest.MaxIdempotencyKeyLength {
+			return problem.Newf(fiber.StatusBadRequest, "%s must be at most %d characters", idempotencyKeyHeader, ingest.MaxIdempotencyKeyLength)
+		}
+
+		var apiKeyID string
+		if apiKey := auth.KeyFrom(c); apiKey != nil {
+			apiKeyID = apiKey.ID.Hex()
+		}
+		result, err := ingestions.Submit(c.UserContext(), &req, apiKeyID, key)
+		if err != nil {
+			return err
+		}
+		if result.Replayed {
+			c.Set("Idempotent-Replayed", "true")
+			return c.Status(fiber.StatusAccepted).JSON(ingestionResponse{Message: "Ingestion was already sent for processing", JobID: result.JobID, Job: result.Job})
+		}
+		return c.Status(fiber.StatusAccepted).JSON(ingestionResponse{Message: "Ingestion is sent for processing", JobID: result.JobID})
+	}
+}
+
+// Deprecated marks the responses of a route as deprecated and links to the
+// route that replaces it.
+func Deprecated(successor string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", "true")
+		c.Append(fiber.HeaderLink, fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		return c.Next()
+	}
+}
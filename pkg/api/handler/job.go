@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var jobStates = map[string]bool{
+	mongo.JobQueued:      true,
+	mongo.JobDownloading: true,
+	mongo.JobExtracting:  true,
+	mongo.JobParsing:     true,
+	mongo.JobIndexing:    true,
+	mongo.JobDone:        true,
+	mongo.JobFailed:      true,
+}
+
+// ListJobsHandler lists the most recent ingestion jobs, optionally in a single state
+//
+// @Summary List ingestion jobs
+// @Tags jobs
+// @Produce json
+// @Param state query string false "Job state" Enums(queued, downloading, extracting, parsing, indexing, done, failed)
+// @Param limit query int false "Number of jobs" minimum(1) maximum(500) default(50)
+// @Success 200 {array} mongo.Job
+// @Failure 400,401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /jobs [get]
+func ListJobsHandler(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 50)
+		if limit < 1 || limit > 500 {
+			return problem.New(fiber.StatusBadRequest, "limit must be between 1 and 500")
+		}
+		state := c.Query("state")
+		if state != "" && !jobStates[state] {
+			return problem.New(fiber.StatusBadRequest, "unknown job state "+state)
+		}
+
+		jobs, err := db.ListJobs(state, int64(limit))
+		if err != nil {
+			return err
+		}
+		return c.JSON(jobs)
+	}
+}
+
+// GetJobHandler returns the state and counters of an ingestion job
+//
+// @Summary Get an ingestion job
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} mongo.Job
+// @Failure 400,401,403,404 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /jobs/{id} [get]
+func GetJobHandler(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		if !primitive.IsValidObjectID(id) {
+			return problem.New(fiber.StatusBadRequest, "invalid job id")
+		}
+
+		job, err := db.RetrieveJob(id)
+		if err != nil {
+			return err
+		}
+		return c.JSON(job)
+	}
+}
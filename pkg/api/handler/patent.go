@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/clbanning/mxj/v2"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PatentHandler returns the normalized record of a patent by storage ID
+//
+// @Summary Get a patent
+// @Tags patents
+// @Produce json
+// @Param id path string true "Storage ID of the patent"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param If-Modified-Since header string false "Date of a cached copy"
+// @Success 200 {object} mongo.Patent
+// @Success 304
+// @Failure 400,401,403,404,429 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /patents/{id} [get]
+func PatentHandler(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		objID, err := mongo.ParseStorageID(id)
+		if err != nil {
+			return problem.New(fiber.StatusBadRequest, "invalid patent id")
+		}
+
+		patent, err := db.RetrievePatent(id)
+		if err != nil {
+			return err
+		}
+
+		body, err := json.Marshal(patent)
+		if err != nil {
+			return err
+		}
+		return sendCacheable(c, body, fiber.MIMEApplicationJSON, objID.Timestamp())
+	}
+}
+
+// RawPatentHandler returns the original document of a patent by storage ID, as
+// JSON or reconstructed XML depending on the format parameter or the Accept header
+//
+// @Summary Get the original document of a patent
+// @Tags patents
+// @Produce json,xml
+// @Param id path string true "Storage ID of the patent"
+// @Param format query string false "Document format, negotiated from the Accept header by default" Enums(json, xml)
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Param If-Modified-Since header string false "Date of a cached copy"
+// @Success 200 {object} object
+// @Success 304
+// @Failure 400,401,403,404,429 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /patents/{id}/raw [get]
+func RawPatentHandler(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		objID, err := mongo.ParseStorageID(id)
+		if err != nil {
+			return problem.New(fiber.StatusBadRequest, "invalid patent id")
+		}
+
+		format := c.Query("format")
+		if format == "" {
+			switch c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMEApplicationXML, fiber.MIMETextXML) {
+			case fiber.MIMEApplicationXML, fiber.MIMETextXML:
+				format = "xml"
+			default:
+				format = "json"
+			}
+		}
+		if format != "json" && format != "xml" {
+			return problem.New(fiber.StatusBadRequest, "format must be json or xml")
+		}
+
+		data, err := db.RetrieveXML(id)
+		if err != nil {
+			return err
+		}
+		document := rawDocument(data)
+
+		var body []byte
+		contentType := fiber.MIMEApplicationJSON
+		if format == "xml" {
+			body, err = mxj.Map(document).XmlIndent("", "  ")
+			contentType = fiber.MIMEApplicationXMLCharsetUTF8
+		} else {
+			body, err = json.Marshal(document)
+		}
+		if err != nil {
+			return err
+		}
+		return sendCacheable(c, body, contentType, objID.Timestamp())
+	}
+}
+
+// rawDocument strips the fields added at storage time from a stored XML
+// document and converts BSON arrays back to the slices produced by mxj
+func rawDocument(data map[string]interface{}) map[string]interface{} {
+	delete(data, "_id")
+	delete(data, "indexing")
+	return fromBSON(data).(map[string]interface{})
+}
+
+func fromBSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = fromBSON(child)
+		}
+		return v
+	case primitive.M:
+		return fromBSON(map[string]interface{}(v))
+	case primitive.D:
+		return fromBSON(map[string]interface{}(v.Map()))
+	case primitive.A:
+		return fromBSON([]interface{}(v))
+	case []interface{}:
+		for i, child := range v {
+			v[i] = fromBSON(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// sendCacheable sends body with an ETag and a Last-Modified header, or 304
+// Not Modified when the client copy is still fresh
+func sendCacheable(c *fiber.Ctx, body []byte, contentType string, modified time.Time) error {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	if notModified(c, etag, modified) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(body)
+}
+
+// notModified evaluates the conditional request headers. If-None-Match takes
+// precedence over If-Modified-Since, as required by RFC 9110.
+func notModified(c *fiber.Ctx, etag string, modified time.Time) bool {
+	if match := c.Get(fiber.HeaderIfNoneMatch); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if since := c.Get(fiber.HeaderIfModifiedSince); since != "" {
+		sinceTime, err := http.ParseTime(since)
+		return err == nil && !modified.Truncate(time.Second).After(sinceTime)
+	}
+	return false
+}
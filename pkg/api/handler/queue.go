@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/events"
+	"github.com/avyukth/search-app/pkg/queue"
+	"github.com/gofiber/fiber/v2"
+)
+
+type queueResponse struct {
+	Stats queue.Stats      `json:"stats"`
+	Tasks []queue.TaskInfo `json:"tasks"`
+}
+
+type workersRequest struct {
+	Workers int `json:"workers" validate:"required" minimum:"1" maximum:"64"`
+}
+
+// QueueHandler lists the running and queued ingestion tasks with the state of the queue
+//
+// @Summary List the running and queued tasks
+// @Tags admin
+// @Produce json
+// @Success 200 {object} queueResponse
+// @Failure 401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/queue [get]
+func QueueHandler(q *queue.TaskQueue) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(queueResponse{Stats: q.Stats(), Tasks: q.Tasks()})
+	}
+}
+
+// CancelTaskHandler cancels a queued or running task. The job of a queued
+// task is failed here, with its final event published to broker, and that of
+// a running task by the worker once the task stops.
+//
+// @Summary Cancel a task
+// @Tags admin
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {object} queue.TaskInfo
+// @Failure 400,401,403,404 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/queue/tasks/{id} [delete]
+func CancelTaskHandler(db *mongo.Database, q *queue.TaskQueue, broker *events.Broker) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return problem.New(fiber.StatusBadRequest, "invalid task id")
+		}
+
+		task, err := q.Cancel(id)
+		if err != nil {
+			return err
+		}
+		if task.State == queue.TaskQueued && task.JobID != "" {
+			if err := db.UpdateJobState(task.JobID, mongo.JobFailed, queue.ErrCancelled); err != nil {
+				log.Printf("Error failing job %s: %v", task.JobID, err)
+			}
+			broker.Publish(events.Event{
+				Type:  events.StateEvent,
+				JobID: task.JobID,
+				State: mongo.JobFailed,
+				Error: queue.ErrCancelled.Error(),
+			})
+		}
+		return c.JSON(task)
+	}
+}
+
+// PauseQueueHandler stops the workers from taking queued tasks
+//
+// @Summary Pause the queue
+// @Description Running tasks go on and tasks are still accepted, but none is started until the queue is resumed.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} queue.Stats
+// @Failure 401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/queue/pause [post]
+func PauseQueueHandler(q *queue.TaskQueue) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		q.Pause()
+		return c.JSON(q.Stats())
+	}
+}
+
+// ResumeQueueHandler ends a pause or a drain
+//
+// @Summary Resume the queue
+// @Tags admin
+// @Produce json
+// @Success 200 {object} queue.Stats
+// @Failure 401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/queue/resume [post]
+func ResumeQueueHandler(q *queue.TaskQueue) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		q.Resume()
+		return c.JSON(q.Stats())
+	}
+}
+
+// DrainQueueHandler stops accepting ingestions while the queued tasks are run
+//
+// @Summary Drain the queue
+// @Description New ingestions are rejected with 503 until the queue is resumed, while the queued tasks are run.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} queue.Stats
+// @Failure 401,403 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/queue/drain [post]
+func DrainQueueHandler(q *queue.TaskQueue) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		q.Drain()
+		return c.JSON(q.Stats())
+	}
+}
+
+// SetWorkersHandler changes the number of queue workers
+//
+// @Summary Change the number of workers
+// @Description Extra workers exit once their task is done.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body workersRequest true "Number of workers"
+// @Success 200 {object} queue.Stats
+// @Failure 400,401,403,503 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /admin/queue/workers [put]
+func SetWorkersHandler(q *queue.TaskQueue) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req workersRequest
+		if err := c.BodyParser(&req); err != nil {
+			return problem.New(fiber.StatusBadRequest, "invalid request body")
+		}
+		if req.Workers < 1 || req.Workers > queue.MaxWorkers {
+			return problem.Newf(fiber.StatusBadRequest, "workers must be between 1 and %d", queue.MaxWorkers)
+		}
+
+		if err := q.SetWorkers(req.Workers); err != nil {
+			return err
+		}
+		return c.JSON(q.Stats())
+	}
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/avyukth/search-app/pkg/snapshot"
+	"github.com/avyukth/search-app/pkg/upload"
+)
+
+// The bodies of the responses that are not a model of their own, named so
+// that they appear in the OpenAPI spec.
+
+// ingestionResponse acknowledges an ingestion. Job is only set when the
+// request replayed an idempotency key.
+type ingestionResponse struct {
+	Message string     `json:"message"`
+	JobID   string     `json:"jobId"`
+	Job     *mongo.Job `json:"job,omitempty"`
+}
+
+type uploadResponse struct {
+	Message string       `json:"message"`
+	JobID   string       `json:"jobId"`
+	File    *upload.File `json:"file"`
+}
+
+type suggestResponse struct {
+	Query       string                          `json:"query"`
+	Suggestions map[string][]indexer.Suggestion `json:"suggestions"`
+}
+
+type analyzeResponse struct {
+	Tokens []indexer.AnalyzedToken `json:"tokens"`
+}
+
+type synonymsResponse struct {
+	// Terms is the number of terms with synonyms.
+	Terms int `json:"terms"`
+}
+
+type snapshotsResponse struct {
+	Snapshots []snapshot.Manifest `json:"snapshots"`
+}
+
+type queryCountResponse struct {
+	Since   time.Time          `json:"since"`
+	Queries []mongo.QueryCount `json:"queries"`
+}
+
+type latencyResponse struct {
+	Since time.Time `json:"since"`
+	Count int64     `json:"count"`
+	// LatencyMS maps percentiles, such as "p95", to milliseconds.
+	LatencyMS map[string]float64 `json:"latencyMs"`
+}
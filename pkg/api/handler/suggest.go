@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SuggestHandler returns typeahead suggestions for titles, assignees and inventors
+//
+// @Summary Suggest completions of a partial query
+// @Tags search
+// @Produce json
+// @Param q query string false "Partial query"
+// @Param size query int false "Suggestions per field" minimum(1) maximum(20) default(5)
+// @Param fields query string false "Comma-separated fields among title, assignee and inventor; all by default"
+// @Success 200 {object} suggestResponse
+// @Failure 400,401,403,429 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /suggest [get]
+func SuggestHandler(searchEngine indexer.SearchBackend) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		text := c.Query("q")
+		size := c.QueryInt("size", indexer.DefaultSuggestions)
+		if size < 1 || size > indexer.MaxSuggestions {
+			return problem.New(fiber.StatusBadRequest, "size must be between 1 and 20")
+		}
+
+		fields := []string{"title", "assignee", "inventor"}
+		if f := c.Query("fields"); f != "" {
+			fields = strings.Split(f, ",")
+		}
+		for _, field := range fields {
+			if _, ok := indexer.SuggestFields[field]; !ok {
+				return problem.New(fiber.StatusBadRequest, "unknown suggestion field: "+field)
+			}
+		}
+
+		suggestions, err := searchEngine.Suggest(text, fields, size)
+		if err != nil {
+			return err
+		}
+		return c.JSON(suggestResponse{Query: text, Suggestions: suggestions})
+	}
+}
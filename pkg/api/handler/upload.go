@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/queue"
+	"github.com/avyukth/search-app/pkg/upload"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// uploadReadTimeout bounds each read of an upload. It replaces the server
+	// read timeout, which would otherwise end large uploads.
+	uploadReadTimeout = 30 * time.Second
+	// uploadFormOverhead is allowed on top of the upload size limit for the
+	// multipart boundaries and headers.
+	uploadFormOverhead = 64 << 10
+	// checksumHeader carries the hex SHA-256 checksum an upload must match.
+	checksumHeader = "X-Checksum-Sha256"
+)
+
+// UploadHandler streams the "file" part of a multipart/form-data request to
+// storage, computing its SHA-256 checksum on the way, and enqueues it for
+// processing. The upload is never held in memory.
+//
+// @Summary Upload a grant file and ingest it
+// @Tags ingestion
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "XML, ZIP or TAR file"
+// @Param X-Checksum-Sha256 header string false "Hex SHA-256 checksum the upload must match"
+// @Success 202 {object} uploadResponse
+// @Failure 400,401,403,413,415,422,429,503 {object} problem.Problem
+// @Security ApiKeyAuth
+// @Router /uploads [post]
+func UploadHandler(db *mongo.Database, q *queue.TaskQueue, uploads *upload.Store) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		mediaType, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+		if err != nil || mediaType != fiber.MIMEMultipartForm || params["boundary"] == "" {
+			return problem.New(fiber.StatusBadRequest, "multipart/form-data request expected")
+		}
+		if int64(c.Request().Header.ContentLength()) > uploads.MaxBytes()+uploadFormOverhead {
+			c.Context().SetConnectionClose()
+			return upload.ErrTooLarge
+		}
+
+		file, err := saveUpload(c, uploads, params["boundary"])
+		if err != nil {
+			return uploadError(c, err)
+		}
+
+		jobID, err := db.StoreJob(&mongo.Job{Type: "upload", Source: file.Name})
+		if err != nil {
+			if err := os.RemoveAll(filepath.Dir(file.Path)); err != nil {
+				log.Printf("Error removing upload %s: %v", file.Path, err)
+			}
+			return fmt.Errorf("error creating job: %w", err)
+		}
+
+		task := queue.Task{
+			FilePath: file.Path,
+			Type:     queue.UploadAndProcess,
+			JobID:    jobID,
+		}
+		if err := q.Enqueue(task); err != nil {
+			// The job never runs, so it is failed and its upload removed.
+			if err := db.UpdateJobState(jobID, mongo.JobFailed, err); err != nil {
+				log.Printf("Error failing job %s: %v", jobID, err)
+			}
+			if err := os.RemoveAll(filepath.Dir(file.Path)); err != nil {
+				log.Printf("Error removing upload %s: %v", file.Path, err)
+			}
+			return err
+		}
+		return c.Status(fiber.StatusAccepted).JSON(uploadResponse{Message: "File is sent for processing", JobID: jobID, File: file})
+	}
+}
+
+// saveUpload reads the request body as a multipart form and saves its "file"
+// part. Other parts are skipped.
+func saveUpload(c *fiber.Ctx, uploads *upload.Store, boundary string) (*upload.File, error) {
+	var body io.Reader
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		body = &deadlineReader{conn: c.Context().Conn(), r: stream, timeout: uploadReadTimeout}
+	} else {
+		body = bytes.NewReader(c.Body())
+	}
+
+	form := multipart.NewReader(body, boundary)
+	for {
+		part, err := form.NextPart()
+		if err == io.EOF {
+			return nil, errMissingFile
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errMalformedUpload, err)
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			continue
+		}
+		return uploads.Save(part.FileName(), part, c.Get(checksumHeader))
+	}
+}
+
+var (
+	errMissingFile     = errors.New("file is required")
+	errMalformedUpload = errors.New("malformed multipart request")
+)
+
+func uploadError(c *fiber.Ctx, err error) error {
+	// The rest of a rejected upload is not read, so the connection cannot be
+	// reused.
+	c.Context().SetConnectionClose()
+	if errors.Is(err, errMissingFile) || errors.Is(err, errMalformedUpload) {
+		return problem.New(fiber.StatusBadRequest, err.Error())
+	}
+	return err
+}
+
+// deadlineReader extends the read deadline of conn before every read, so that
+// an upload is only cut off when the client stalls.
+type deadlineReader struct {
+	conn    net.Conn
+	r       io.Reader
+	timeout time.Duration
+}
+
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	d.conn.SetReadDeadline(time.Now().Add(d.timeout))
+	return d.r.Read(p)
+}
+
+// LimitBody rejects request bodies larger than limit with 413. The server
+// streams request bodies to their handler, so routes other than uploads read
+// them through LimitBody, which keeps them bounded as without streaming.
+func LimitBody(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stream := c.Context().RequestBodyStream()
+		if stream == nil {
+			return c.Next()
+		}
+		body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+		if err != nil {
+			return problem.New(fiber.StatusBadRequest, "error reading request body")
+		}
+		if len(body) > limit {
+			c.Context().SetConnectionClose()
+			return problem.New(fiber.StatusRequestEntityTooLarge, "request body too large")
+		}
+		c.Request().SetBody(body)
+		return c.Next()
+	}
+}
@@ -0,0 +1,139 @@
+// Package openapi validates API requests against the OpenAPI spec generated
+// from the handler annotations.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"github.com/gofiber/fiber/v2"
+)
+
+// pathParam matches the parameters of a path template, such as {id}.
+var pathParam = regexp.MustCompile(`\{([^/{}]+)\}`)
+
+// Spec is a loaded OpenAPI spec, with its paths compiled for matching
+// requests.
+type Spec struct {
+	basePath    string
+	definitions spec.Definitions
+	operations  []operation
+}
+
+// operation is an operation of the spec. params lists the names of the path
+// parameters in the order pattern captures them.
+type operation struct {
+	method     string
+	pattern    *regexp.Regexp
+	params     []string
+	parameters []spec.Parameter
+}
+
+// Load parses an OpenAPI 2.0 document, such as docs.SwaggerInfo.ReadDoc().
+func Load(doc string) (*Spec, error) {
+	var swagger spec.Swagger
+	if err := json.Unmarshal([]byte(doc), &swagger); err != nil {
+		return nil, fmt.Errorf("error parsing OpenAPI spec: %w", err)
+	}
+	if swagger.Paths == nil {
+		return nil, fmt.Errorf("OpenAPI spec has no paths")
+	}
+
+	s := &Spec{basePath: strings.TrimSuffix(swagger.BasePath, "/"), definitions: swagger.Definitions}
+	for path, item := range swagger.Paths.Paths {
+		pattern, params := compilePath(path)
+		for method, op := range map[string]*spec.Operation{
+			fiber.MethodGet:     item.Get,
+			fiber.MethodPut:     item.Put,
+			fiber.MethodPost:    item.Post,
+			fiber.MethodDelete:  item.Delete,
+			fiber.MethodOptions: item.Options,
+			fiber.MethodHead:    item.Head,
+			fiber.MethodPatch:   item.Patch,
+		} {
+			if op == nil {
+				continue
+			}
+			s.operations = append(s.operations, operation{
+				method:     method,
+				pattern:    pattern,
+				params:     params,
+				parameters: append(append([]spec.Parameter(nil), item.Parameters...), op.Parameters...),
+			})
+		}
+	}
+	// Paths with fewer parameters are more specific, so /jobs/stats would
+	// be matched before /jobs/{id}.
+	sort.SliceStable(s.operations, func(i, j int) bool {
+		return len(s.operations[i].params) < len(s.operations[j].params)
+	})
+	return s, nil
+}
+
+// compilePath turns a path template into a regular expression capturing its
+// parameters.
+func compilePath(path string) (*regexp.Regexp, []string) {
+	var params []string
+	var pattern strings.Builder
+	pattern.WriteByte('^')
+	last := 0
+	for _, loc := range pathParam.FindAllStringSubmatchIndex(path, -1) {
+		pattern.WriteString(regexp.QuoteMeta(path[last:loc[0]]))
+		pattern.WriteString("([^/]+)")
+		params = append(params, path[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(path[last:]))
+	pattern.WriteByte('$')
+	return regexp.MustCompile(pattern.String()), params
+}
+
+// match returns the operation of a request and the values of its path
+// parameters.
+func (s *Spec) match(method, path string) (*operation, map[string]string) {
+	path, ok := strings.CutPrefix(path, s.basePath)
+	if !ok {
+		return nil, nil
+	}
+	// HEAD requests are served by GET routes.
+	if method == fiber.MethodHead {
+		method = fiber.MethodGet
+	}
+	for i := range s.operations {
+		op := &s.operations[i]
+		if op.method != method {
+			continue
+		}
+		values := op.pattern.FindStringSubmatch(path)
+		if values == nil {
+			continue
+		}
+		params := make(map[string]string, len(op.params))
+		for j, name := range op.params {
+			params[name] = values[j+1]
+		}
+		return op, params
+	}
+	return nil, nil
+}
+
+// Validate rejects requests whose parameters or JSON body do not match their
+// operation in the spec with a *ValidationError. Requests for paths or
+// methods the spec does not describe are passed on, so that they get the
+// usual 404 or 405.
+func (s *Spec) Validate() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		op, params := s.match(c.Method(), c.Path())
+		if op == nil {
+			return c.Next()
+		}
+		if verr := s.validateRequest(c, op, params); verr != nil {
+			return verr
+		}
+		return c.Next()
+	}
+}
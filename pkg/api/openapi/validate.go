@@ -0,0 +1,272 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-openapi/spec"
+	"github.com/gofiber/fiber/v2"
+)
+
+// definitionPrefix is the prefix of the references to the definitions of the
+// spec.
+const definitionPrefix = "#/definitions/"
+
+// FieldError describes a single problem with a request. Path names the
+// parameter, such as query.size, or the body member, such as body.query.match.
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found in a request.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		messages = append(messages, fe.Path+": "+fe.Message)
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) add(path, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// validateRequest checks the parameters and the body of a request. Form
+// parameters are left to the handler, which streams them.
+func (s *Spec) validateRequest(c *fiber.Ctx, op *operation, pathValues map[string]string) *ValidationError {
+	verr := &ValidationError{}
+	for _, param := range op.parameters {
+		switch param.In {
+		case "path":
+			value, err := url.PathUnescape(pathValues[param.Name])
+			if err != nil {
+				verr.add("path."+param.Name, "is not a valid path segment")
+				continue
+			}
+			validateParam("path."+param.Name, &param, value, verr)
+		case "query":
+			validateParam("query."+param.Name, &param, c.Query(param.Name), verr)
+		case "header":
+			validateParam("header."+param.Name, &param, c.Get(param.Name), verr)
+		case "body":
+			s.validateBody(c, &param, verr)
+		}
+	}
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// validateParam checks a path, query or header parameter. Empty values are
+// taken as missing, as the handlers do.
+func validateParam(path string, param *spec.Parameter, value string, verr *ValidationError) {
+	if value == "" {
+		if param.Required {
+			verr.add(path, "is required")
+		}
+		return
+	}
+
+	switch param.Type {
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			verr.add(path, "must be an integer")
+			return
+		}
+		validateNumber(path, &param.CommonValidations, float64(n), verr)
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			verr.add(path, "must be a number")
+			return
+		}
+		validateNumber(path, &param.CommonValidations, n, verr)
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			verr.add(path, "must be a boolean")
+		}
+	default:
+		validateString(path, &param.CommonValidations, value, verr)
+	}
+	validateEnum(path, param.Enum, value, verr)
+}
+
+// validateBody checks a JSON request body against the schema of the body
+// parameter. Bodies of other media types are left to the handler.
+func (s *Spec) validateBody(c *fiber.Ctx, param *spec.Parameter, verr *ValidationError) {
+	body := c.Body()
+	if len(bytes.TrimSpace(body)) == 0 {
+		if param.Required {
+			verr.add("body", "is required")
+		}
+		return
+	}
+	if contentType := c.Get(fiber.HeaderContentType); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !strings.HasSuffix(mediaType, "json") {
+			return
+		}
+	}
+	if param.Schema == nil {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		verr.add("body", "is not valid JSON: %v", err)
+		return
+	}
+	s.validateSchema("body", param.Schema, value, verr)
+}
+
+// validateSchema checks a decoded JSON value against a schema, following
+// references to the definitions of the spec.
+func (s *Spec) validateSchema(path string, schema *spec.Schema, value interface{}, verr *ValidationError) {
+	if ref := schema.Ref.String(); ref != "" {
+		definition, ok := s.definitions[strings.TrimPrefix(ref, definitionPrefix)]
+		if !ok {
+			return
+		}
+		schema = &definition
+	}
+	for i := range schema.AllOf {
+		s.validateSchema(path, &schema.AllOf[i], value, verr)
+	}
+	if value == nil {
+		// null stands for the zero value, as encoding/json decodes it.
+		return
+	}
+
+	validations := schema.Validations()
+	switch {
+	case schema.Type.Contains("object"):
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			verr.add(path, "must be an object")
+			return
+		}
+		s.validateObject(path, schema, object, verr)
+	case schema.Type.Contains("array"):
+		array, ok := value.([]interface{})
+		if !ok {
+			verr.add(path, "must be an array")
+			return
+		}
+		if validations.MinItems != nil && int64(len(array)) < *validations.MinItems {
+			verr.add(path, "must have at least %d items", *validations.MinItems)
+		}
+		if validations.MaxItems != nil && int64(len(array)) > *validations.MaxItems {
+			verr.add(path, "must have at most %d items", *validations.MaxItems)
+		}
+		if schema.Items != nil && schema.Items.Schema != nil {
+			for i, item := range array {
+				s.validateSchema(fmt.Sprintf("%s[%d]", path, i), schema.Items.Schema, item, verr)
+			}
+		}
+	case schema.Type.Contains("string"):
+		str, ok := value.(string)
+		if !ok {
+			verr.add(path, "must be a string")
+			return
+		}
+		validateString(path, &validations.CommonValidations, str, verr)
+		validateEnum(path, schema.Enum, str, verr)
+	case schema.Type.Contains("integer"), schema.Type.Contains("number"):
+		number, ok := value.(json.Number)
+		if !ok {
+			verr.add(path, "must be a number")
+			return
+		}
+		if schema.Type.Contains("integer") {
+			if _, err := number.Int64(); err != nil {
+				verr.add(path, "must be an integer")
+				return
+			}
+		}
+		n, err := number.Float64()
+		if err != nil {
+			verr.add(path, "must be a number")
+			return
+		}
+		validateNumber(path, &validations.CommonValidations, n, verr)
+		validateEnum(path, schema.Enum, number.String(), verr)
+	case schema.Type.Contains("boolean"):
+		if _, ok := value.(bool); !ok {
+			verr.add(path, "must be a boolean")
+		}
+	}
+}
+
+// validateObject checks the required and known members of an object, and the
+// values of maps.
+func (s *Spec) validateObject(path string, schema *spec.Schema, object map[string]interface{}, verr *ValidationError) {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			verr.add(path+"."+name, "is required")
+		}
+	}
+
+	// Members are checked in order, so that errors are reported in the same
+	// order for the same body.
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := schema.Properties[name]; ok {
+			s.validateSchema(path+"."+name, &property, object[name], verr)
+		} else if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+			s.validateSchema(path+"."+name, schema.AdditionalProperties.Schema, object[name], verr)
+		}
+	}
+}
+
+func validateNumber(path string, v *spec.CommonValidations, n float64, verr *ValidationError) {
+	if v.Minimum != nil && (n < *v.Minimum || v.ExclusiveMinimum && n == *v.Minimum) {
+		verr.add(path, "must be at least %v", *v.Minimum)
+	}
+	if v.Maximum != nil && (n > *v.Maximum || v.ExclusiveMaximum && n == *v.Maximum) {
+		verr.add(path, "must be at most %v", *v.Maximum)
+	}
+}
+
+func validateString(path string, v *spec.CommonValidations, str string, verr *ValidationError) {
+	length := int64(utf8.RuneCountInString(str))
+	if v.MinLength != nil && length < *v.MinLength {
+		verr.add(path, "must be at least %d characters", *v.MinLength)
+	}
+	if v.MaxLength != nil && length > *v.MaxLength {
+		verr.add(path, "must be at most %d characters", *v.MaxLength)
+	}
+}
+
+func validateEnum(path string, enum []interface{}, value string, verr *ValidationError) {
+	if len(enum) == 0 {
+		return
+	}
+	allowed := make([]string, len(enum))
+	for i, e := range enum {
+		allowed[i] = fmt.Sprint(e)
+		if allowed[i] == value {
+			return
+		}
+	}
+	verr.add(path, "must be one of %s", strings.Join(allowed, ", "))
+}
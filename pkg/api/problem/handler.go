@@ -0,0 +1,100 @@
+package problem
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/avyukth/search-app/pkg/api/openapi"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/downloader"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/avyukth/search-app/pkg/ingest"
+	"github.com/avyukth/search-app/pkg/queue"
+	"github.com/avyukth/search-app/pkg/snapshot"
+	"github.com/avyukth/search-app/pkg/upload"
+	"github.com/gofiber/fiber/v2"
+)
+
+// domainError is the problem an error of a domain package is reported as.
+type domainError struct {
+	err    error
+	status int
+	name   string
+	title  string
+}
+
+// domainErrors lists the domain errors handlers pass on to ErrorHandler. The
+// first one an error matches wins.
+var domainErrors = []domainError{
+	{mongo.ErrNotFound, fiber.StatusNotFound, "not-found", "Resource not found"},
+	{indexer.ErrNotFound, fiber.StatusNotFound, "not-found", "Resource not found"},
+	{snapshot.ErrNotFound, fiber.StatusNotFound, "not-found", "Resource not found"},
+	{indexer.ErrInvalidAnalyzer, fiber.StatusBadRequest, "invalid-analyzer", "Invalid analyzer"},
+	{indexer.ErrSnapshotUnsupported, fiber.StatusNotImplemented, "snapshot-unsupported", "Snapshots not supported"},
+	{snapshot.ErrInvalidSnapshot, fiber.StatusBadRequest, "invalid-snapshot", "Invalid snapshot"},
+	{snapshot.ErrMappingVersion, fiber.StatusConflict, "mapping-version", "Snapshot mapping mismatch"},
+	{downloader.ErrLinkNotLive, fiber.StatusNotFound, "link-not-live", "Link not live"},
+	{ingest.ErrAlreadyProcessed, fiber.StatusConflict, "already-processed", "Link already processed"},
+	{ingest.ErrKeyInProgress, fiber.StatusConflict, "idempotency-key-in-progress", "Idempotency key in progress"},
+	{ingest.ErrKeyReused, fiber.StatusUnprocessableEntity, "idempotency-key-reused", "Idempotency key reused"},
+	{queue.ErrFull, fiber.StatusServiceUnavailable, "queue-full", "Task queue full"},
+	{queue.ErrStopped, fiber.StatusServiceUnavailable, "queue-stopped", "Task queue stopped"},
+	{queue.ErrDraining, fiber.StatusServiceUnavailable, "queue-draining", "Task queue draining"},
+	{queue.ErrTaskNotFound, fiber.StatusNotFound, "not-found", "Resource not found"},
+	{upload.ErrUnsupportedType, fiber.StatusUnsupportedMediaType, "unsupported-upload", "Unsupported upload type"},
+	{upload.ErrTooLarge, fiber.StatusRequestEntityTooLarge, "upload-too-large", "Upload too large"},
+	{upload.ErrChecksumMismatch, fiber.StatusUnprocessableEntity, "checksum-mismatch", "Checksum mismatch"},
+}
+
+// From returns the problem an error is reported as. Errors that are neither
+// problems, Fiber errors nor domain errors are internal server errors, whose
+// detail is only logged.
+func From(err error) *Problem {
+	var p *Problem
+	var ferr *fiber.Error
+	var verr *indexer.ValidationError
+	var rerr *openapi.ValidationError
+	switch {
+	case errors.As(err, &p):
+		return p
+	case errors.As(err, &ferr):
+		return New(ferr.Code, ferr.Message)
+	case errors.As(err, &verr):
+		p := Typed(fiber.StatusBadRequest, "query-rejected", "Query rejected", verr)
+		p.Errors = verr.Errors
+		return p
+	case errors.As(err, &rerr):
+		p := Typed(fiber.StatusBadRequest, "invalid-request", "Invalid request", rerr)
+		p.Errors = rerr.Errors
+		return p
+	case errors.Is(err, context.DeadlineExceeded):
+		return Typed(fiber.StatusServiceUnavailable, "search-timeout", "Search timed out",
+			errors.New("search exceeded its time limit, narrow the query and retry"))
+	}
+	for _, d := range domainErrors {
+		if errors.Is(err, d.err) {
+			return Typed(d.status, d.name, d.title, err)
+		}
+	}
+	return &Problem{
+		Type:   blankType,
+		Title:  "Internal Server Error",
+		Status: fiber.StatusInternalServerError,
+		Detail: "the server failed to handle the request, retry later or report the request ID",
+		err:    err,
+	}
+}
+
+// ErrorHandler is the Fiber error handler of the API. It sends the error
+// returned by a handler as problem details carrying the request ID set by the
+// requestid middleware.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	p := *From(err)
+	p.Instance = c.Path()
+	p.RequestID = c.GetRespHeader(fiber.HeaderXRequestID)
+	if p.Status >= fiber.StatusInternalServerError {
+		log.Printf("Error handling %s %s (request %s): %v", c.Method(), c.Path(), p.RequestID, err)
+	}
+	return c.Status(p.Status).JSON(p, ContentType)
+}
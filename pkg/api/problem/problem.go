@@ -0,0 +1,58 @@
+// Package problem reports API errors as RFC 7807 problem details.
+package problem
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ContentType is the media type of problem details responses.
+const ContentType = "application/problem+json"
+
+// blankType is the problem type of errors that mean no more than their
+// status code.
+const blankType = "about:blank"
+
+// Problem is an RFC 7807 problem details object. Handlers return it as an
+// error, and ErrorHandler sends it with the ID of the request.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	// Errors lists the individual reasons a request was rejected for, such as
+	// the invalid clauses of a query.
+	Errors interface{} `json:"errors,omitempty"`
+
+	err error
+}
+
+// New returns a problem with no more meaning than its status code.
+func New(status int, detail string) *Problem {
+	return &Problem{Type: blankType, Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// Newf is New with a formatted detail.
+func Newf(status int, format string, args ...interface{}) *Problem {
+	return New(status, fmt.Sprintf(format, args...))
+}
+
+// Typed returns a problem of a type listed in the API documentation, which
+// clients can tell apart from other problems with the same status code.
+func Typed(status int, name, title string, err error) *Problem {
+	return &Problem{Type: "/problems/" + name, Title: title, Status: status, Detail: err.Error(), err: err}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Detail
+}
+
+// Unwrap returns the error the problem reports, if any.
+func (p *Problem) Unwrap() error {
+	return p.err
+}
@@ -1,29 +1,103 @@
 package router
 
 import (
+	"github.com/avyukth/search-app/pkg/analytics"
+	"github.com/avyukth/search-app/pkg/api/gql"
 	"github.com/avyukth/search-app/pkg/api/handler"
+	"github.com/avyukth/search-app/pkg/api/openapi"
+	"github.com/avyukth/search-app/pkg/auth"
 	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/events"
+	"github.com/avyukth/search-app/pkg/health"
 	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/avyukth/search-app/pkg/ingest"
 	"github.com/avyukth/search-app/pkg/queue"
+	"github.com/avyukth/search-app/pkg/ratelimit"
+	"github.com/avyukth/search-app/pkg/snapshot"
+	"github.com/avyukth/search-app/pkg/upload"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 )
 
-// SetupRoutes sets up all the routes for your application
-func SetupRoutes(app *fiber.App, db *mongo.Database, searchEngine *indexer.SearchEngine, q *queue.TaskQueue) {
+// The OpenAPI spec served at /swagger and checked by openapi.Spec is generated
+// from the annotations of main and the handlers.
+//go:generate swag init -d ../../.. -g cmd/server/main.go -o ../../../docs --overridesFile ../../../.swaggo --propertyStrategy pascalcase
 
+// SetupRoutes sets up all the routes for your application. When authEnabled
+// is set, every route but the health checks requires an API key with the
+// role named next to it. Search and ingestion routes are rate limited per
+// caller by limits. Requests are validated against spec, which must describe
+// every route.
+func SetupRoutes(app *fiber.App, db *mongo.Database, searchEngine indexer.SearchBackend, q *queue.TaskQueue, ingestions *ingest.Submitter, snapshots *snapshot.Manager, queryLog *analytics.Recorder, broker *events.Broker, uploads *upload.Store, authEnabled bool, limits *ratelimit.Limits, schema *gql.Schema, spec *openapi.Spec, checker *health.Checker) {
+
+	// Request IDs are set first, so that every log line and error carries one
+	app.Use(requestid.New())
+	// Handlers get a context cancelled when the client goes away
+	app.Use(handler.RequestContext())
 	// logger Middleware
-	app.Use(logger.New())
+	app.Use(logger.New(logger.Config{
+		Format: "${time} | ${respHeader:X-Request-ID} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${error}\n",
+	}))
 
 	// Routes
 	api := app.Group("/api")
 	v1 := api.Group("/v1")
-	//go:generate swagger generate spec -o swagger.json
-	v1.Get("/search", handler.SearchHandler(db, searchEngine))
-	v1.Get("/download", handler.DownloadHandler(db, q))
-	v1.Get("/crawl", handler.CrawlerHandler(db, q))
-	v1.Get("/live", func(c *fiber.Ctx) error {
-		return c.SendString("OK")
-	})
+	// Deprecated: replaced by /healthz, which checks the dependencies
+	v1.Get("/live", handler.Deprecated("/api/v1/healthz"), handler.LiveHandler())
+	v1.Get("/healthz", handler.HealthzHandler(checker))
+	v1.Get("/readyz", handler.ReadyzHandler(checker))
+
+	if authEnabled {
+		v1.Use(auth.Authenticate(db))
+	}
+	reader := auth.Require(auth.RoleReader)
+	ingestor := auth.Require(auth.RoleIngestor)
+	searchLimit := limits.Search.Handler()
+	ingestionLimit := limits.Ingestion.Handler()
+
+	// Uploads stream their body, so they are registered before the body limit
+	// that every other route reads its body through.
+	v1.Post("/uploads", ingestor, ingestionLimit, handler.UploadHandler(db, q, uploads))
+	v1.Use(handler.LimitBody(fiber.DefaultBodyLimit))
+	v1.Use(spec.Validate())
+	v1.Get("/search", reader, searchLimit, handler.SearchHandler(db, searchEngine, queryLog))
+	v1.Post("/search", reader, searchLimit, handler.AdvancedSearchHandler(searchEngine, queryLog))
+	v1.Get("/search/inventors", reader, searchLimit, handler.InventorSearchHandler(searchEngine))
+	v1.Get("/search/export", reader, searchLimit, handler.ExportHandler(searchEngine))
+	v1.Get("/suggest", reader, searchLimit, handler.SuggestHandler(searchEngine))
+	v1.Get("/patents/:id", reader, searchLimit, handler.PatentHandler(db))
+	v1.Get("/patents/:id/raw", reader, searchLimit, handler.RawPatentHandler(db))
+	v1.Post("/saved-searches", reader, handler.CreateSavedSearchHandler(db))
+	v1.Get("/saved-searches", reader, handler.ListSavedSearchesHandler(db))
+	v1.Delete("/saved-searches/:id", reader, handler.DeleteSavedSearchHandler(db))
+	v1.Get("/alerts", reader, handler.ListAlertsHandler(db))
+	v1.Get("/graphql", reader, searchLimit, handler.GraphQLHandler(schema))
+	v1.Post("/graphql", reader, searchLimit, handler.GraphQLHandler(schema))
+
+	v1.Post("/ingestions", ingestor, ingestionLimit, handler.CreateIngestionHandler(ingestions))
+	// Deprecated: GET routes with side effects, replaced by POST /ingestions
+	v1.Get("/download", ingestor, ingestionLimit, handler.Deprecated("/api/v1/ingestions"), handler.DownloadHandler(ingestions))
+	v1.Get("/crawl", ingestor, ingestionLimit, handler.Deprecated("/api/v1/ingestions"), handler.CrawlerHandler(ingestions))
+	v1.Get("/jobs", ingestor, handler.ListJobsHandler(db))
+	v1.Get("/jobs/:id", ingestor, handler.GetJobHandler(db))
+	v1.Get("/jobs/:id/events", ingestor, handler.JobEventsHandler(db, broker))
+
+	reports := v1.Group("/analytics", auth.Require(auth.RoleAdmin))
+	reports.Get("/top-queries", handler.TopQueriesHandler(db))
+	reports.Get("/zero-results", handler.ZeroResultQueriesHandler(db))
+	reports.Get("/latency", handler.SearchLatencyHandler(db))
 
+	admin := v1.Group("/admin", auth.Require(auth.RoleAdmin))
+	admin.Post("/analyze", handler.AnalyzeHandler(searchEngine))
+	admin.Post("/synonyms/reload", handler.ReloadSynonymsHandler())
+	admin.Post("/snapshots", handler.CreateSnapshotHandler(snapshots))
+	admin.Get("/snapshots", handler.ListSnapshotsHandler(snapshots))
+	admin.Post("/snapshots/:name/restore", handler.RestoreSnapshotHandler(snapshots))
+	admin.Get("/queue", handler.QueueHandler(q))
+	admin.Delete("/queue/tasks/:id", handler.CancelTaskHandler(db, q, broker))
+	admin.Post("/queue/pause", handler.PauseQueueHandler(q))
+	admin.Post("/queue/resume", handler.ResumeQueueHandler(q))
+	admin.Post("/queue/drain", handler.DrainQueueHandler(q))
+	admin.Put("/queue/workers", handler.SetWorkersHandler(q))
 }
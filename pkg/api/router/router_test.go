@@ -0,0 +1,63 @@
+package router
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/avyukth/search-app/docs"
+	"github.com/avyukth/search-app/pkg/api/openapi"
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// fiberParam matches the parameters of a Fiber route, such as :id.
+var fiberParam = regexp.MustCompile(`:([^/]+)`)
+
+// TestRoutesDocumented fails when a route is missing from the OpenAPI spec,
+// or the spec describes a route that does not exist. Annotate the handler and
+// run go generate ./pkg/api/router to fix it.
+func TestRoutesDocumented(t *testing.T) {
+	doc := docs.SwaggerInfo.ReadDoc()
+	spec, err := openapi.Load(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var swagger struct {
+		BasePath string                                `json:"basePath"`
+		Paths    map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(doc), &swagger); err != nil {
+		t.Fatal(err)
+	}
+	documented := map[string]bool{}
+	for path, operations := range swagger.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	app := fiber.New()
+	SetupRoutes(app, nil, nil, nil, nil, nil, nil, nil, nil, true, ratelimit.NewLimits(nil, &config.RateLimitConfig{}), nil, spec, nil)
+
+	routed := map[string]bool{}
+	for _, route := range app.GetRoutes(true) {
+		path, ok := strings.CutPrefix(route.Path, swagger.BasePath)
+		// Fiber serves HEAD requests with the GET routes.
+		if !ok || route.Method == fiber.MethodHead {
+			continue
+		}
+		route := route.Method + " " + fiberParam.ReplaceAllString(path, "{$1}")
+		routed[route] = true
+		if !documented[route] {
+			t.Errorf("%s is missing from the OpenAPI spec", route)
+		}
+	}
+	for route := range documented {
+		if !routed[route] {
+			t.Errorf("%s is in the OpenAPI spec but not routed", route)
+		}
+	}
+}
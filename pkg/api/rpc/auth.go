@@ -0,0 +1,109 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/avyukth/search-app/pkg/api/rpc/searchpb"
+	"github.com/avyukth/search-app/pkg/auth"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodRoles is the role required by each method, matching the role of the
+// HTTP route it mirrors. Methods missing from it require the admin role.
+var methodRoles = map[string]string{
+	searchpb.PatentSearch_Search_FullMethodName:          auth.RoleReader,
+	searchpb.PatentSearch_GetPatent_FullMethodName:       auth.RoleReader,
+	searchpb.PatentSearch_CreateIngestion_FullMethodName: auth.RoleIngestor,
+	searchpb.PatentSearch_GetJob_FullMethodName:          auth.RoleIngestor,
+	searchpb.PatentSearch_WatchJob_FullMethodName:        auth.RoleIngestor,
+}
+
+type apiKeyContextKey struct{}
+
+// keyFrom returns the API key of an authenticated call, or nil.
+func keyFrom(ctx context.Context) *mongo.APIKey {
+	apiKey, _ := ctx.Value(apiKeyContextKey{}).(*mongo.APIKey)
+	return apiKey
+}
+
+// authenticator checks the API key of every call, like auth.Authenticate and
+// auth.Require do for the HTTP routes.
+type authenticator struct {
+	db *mongo.Database
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate returns ctx carrying the API key of the call, or an
+// Unauthenticated or PermissionDenied error.
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	key := metadataKey(ctx)
+	if key == "" {
+		return nil, status.Error(codes.Unauthenticated, "API key required")
+	}
+	apiKey, err := a.db.RetrieveAPIKeyByHash(auth.HashKey(key))
+	if errors.Is(err, mongo.ErrNotFound) {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	if err != nil {
+		log.Printf("Error authenticating API key: %v", err)
+		return nil, status.Error(codes.Internal, "error authenticating API key")
+	}
+
+	role, ok := methodRoles[method]
+	if !ok {
+		role = auth.RoleAdmin
+	}
+	if !auth.Allows(apiKey, role) {
+		return nil, status.Errorf(codes.PermissionDenied, "this method requires the %s role", role)
+	}
+	return context.WithValue(ctx, apiKeyContextKey{}, apiKey), nil
+}
+
+// metadataKey returns the key of the x-api-key metadata or, failing that, of
+// a Bearer authorization.
+func metadataKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(strings.ToLower(auth.APIKeyHeader)); len(keys) > 0 && keys[0] != "" {
+		return keys[0]
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// authenticatedStream replaces the context of a stream with one carrying the
+// API key.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
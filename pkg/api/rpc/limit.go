@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/avyukth/search-app/pkg/api/rpc/searchpb"
+	"github.com/avyukth/search-app/pkg/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// rateLimiter applies the rate limits of the HTTP routes to the methods
+// mirroring them, sharing their token buckets and daily quotas.
+type rateLimiter struct {
+	limits *ratelimit.Limits
+}
+
+// limiter returns the limiter of the route group of method, or nil when the
+// matching HTTP route is not limited.
+func (r *rateLimiter) limiter(method string) *ratelimit.Limiter {
+	switch method {
+	case searchpb.PatentSearch_Search_FullMethodName, searchpb.PatentSearch_GetPatent_FullMethodName:
+		return r.limits.Search
+	case searchpb.PatentSearch_CreateIngestion_FullMethodName:
+		return r.limits.Ingestion
+	}
+	return nil
+}
+
+// unary rejects calls over the limits of their group with ResourceExhausted
+// and a retry-after header. It runs after the authenticator, so that callers
+// are told by API key, or by client IP when authentication is disabled.
+func (r *rateLimiter) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if l := r.limiter(info.FullMethod); l != nil {
+		d := l.Allow(keyFrom(ctx), peerIP(ctx))
+		if !d.Allowed {
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(d.RetryAfterSeconds())))
+			return nil, status.Error(codes.ResourceExhausted, d.Reason)
+		}
+	}
+	return handler(ctx, req)
+}
+
+// peerIP returns the IP of the client of a call, or its address when it has
+// no port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
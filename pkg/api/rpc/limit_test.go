@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/avyukth/search-app/pkg/api/rpc/searchpb"
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/ratelimit"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func testLimits(burst int) *ratelimit.Limits {
+	policy := config.RateLimitPolicy{PerMinute: 1, Burst: burst}
+	return ratelimit.NewLimits(nil, &config.RateLimitConfig{Search: policy, Ingestion: policy})
+}
+
+// dialServer serves srv over an in-memory listener and returns a client.
+func dialServer(t *testing.T, server *grpc.Server) searchpb.PatentSearchClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return searchpb.NewPatentSearchClient(conn)
+}
+
+func TestRateLimitExhausted(t *testing.T) {
+	client := dialServer(t, NewGRPCServer(&Server{}, false, testLimits(2)))
+	ctx := context.Background()
+
+	// Empty queries are rejected by the handler, so calls within the limit
+	// get InvalidArgument without touching the search engine.
+	for i := 0; i < 2; i++ {
+		_, err := client.Search(ctx, &searchpb.SearchRequest{})
+		if code := status.Code(err); code != codes.InvalidArgument {
+			t.Fatalf("call %d: got %v, want InvalidArgument", i+1, code)
+		}
+	}
+
+	var header metadata.MD
+	_, err := client.Search(ctx, &searchpb.SearchRequest{}, grpc.Header(&header))
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Fatalf("call past the limit: got %v, want ResourceExhausted", code)
+	}
+	if got := header.Get("retry-after"); len(got) != 1 || got[0] != "60" {
+		t.Errorf("retry-after = %v, want [60]", got)
+	}
+
+	// GetPatent shares the bucket of Search, while CreateIngestion has its
+	// own.
+	if _, err := client.GetPatent(ctx, &searchpb.GetPatentRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("GetPatent: got %v, want ResourceExhausted", status.Code(err))
+	}
+	if _, err := client.CreateIngestion(ctx, &searchpb.CreateIngestionRequest{}); status.Code(err) == codes.ResourceExhausted {
+		t.Errorf("CreateIngestion: got ResourceExhausted from the search bucket")
+	}
+}
+
+func TestRateLimitByAPIKey(t *testing.T) {
+	limiter := &rateLimiter{limits: testLimits(1)}
+	info := &grpc.UnaryServerInfo{FullMethod: searchpb.PatentSearch_Search_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	withKey := func() context.Context {
+		return context.WithValue(context.Background(), apiKeyContextKey{}, &mongo.APIKey{ID: primitive.NewObjectID()})
+	}
+	first, second := withKey(), withKey()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"first key", first, codes.OK},
+		{"second key", second, codes.OK},
+		{"first key again", first, codes.ResourceExhausted},
+		{"second key again", second, codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		_, err := limiter.unary(tt.ctx, nil, info, handler)
+		if code := status.Code(err); code != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, code, tt.want)
+		}
+	}
+}
+
+func TestRateLimitUnlimitedMethod(t *testing.T) {
+	limiter := &rateLimiter{limits: testLimits(1)}
+	info := &grpc.UnaryServerInfo{FullMethod: searchpb.PatentSearch_GetJob_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	for i := 0; i < 3; i++ {
+		if _, err := limiter.unary(context.Background(), nil, info, handler); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+}
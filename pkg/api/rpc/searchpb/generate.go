@@ -0,0 +1,5 @@
+// Package searchpb holds the protobuf messages and gRPC service of the search
+// API, generated from search.proto.
+package searchpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative search.proto
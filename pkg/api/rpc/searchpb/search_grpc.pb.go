@@ -0,0 +1,307 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.24.4
+// source: search.proto
+
+package searchpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PatentSearch_Search_FullMethodName          = "/search.v1.PatentSearch/Search"
+	PatentSearch_GetPatent_FullMethodName       = "/search.v1.PatentSearch/GetPatent"
+	PatentSearch_CreateIngestion_FullMethodName = "/search.v1.PatentSearch/CreateIngestion"
+	PatentSearch_GetJob_FullMethodName          = "/search.v1.PatentSearch/GetJob"
+	PatentSearch_WatchJob_FullMethodName        = "/search.v1.PatentSearch/WatchJob"
+)
+
+// PatentSearchClient is the client API for PatentSearch service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PatentSearchClient interface {
+	// Search runs a query string search, like GET /api/v1/search. Requires the
+	// reader role.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// GetPatent returns a patent by storage ID, like GET /api/v1/patents/{id}.
+	// Requires the reader role.
+	GetPatent(ctx context.Context, in *GetPatentRequest, opts ...grpc.CallOption) (*Patent, error)
+	// CreateIngestion starts an ingestion job, like POST /api/v1/ingestions.
+	// Requires the ingestor role.
+	CreateIngestion(ctx context.Context, in *CreateIngestionRequest, opts ...grpc.CallOption) (*CreateIngestionResponse, error)
+	// GetJob returns an ingestion job, like GET /api/v1/jobs/{id}. Requires the
+	// ingestor role.
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// WatchJob streams the state changes and file counters of an ingestion job
+	// until it finishes, like GET /api/v1/jobs/{id}/events. The first event is
+	// the current state of the job. Requires the ingestor role.
+	WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (PatentSearch_WatchJobClient, error)
+}
+
+type patentSearchClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPatentSearchClient(cc grpc.ClientConnInterface) PatentSearchClient {
+	return &patentSearchClient{cc}
+}
+
+func (c *patentSearchClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, PatentSearch_Search_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *patentSearchClient) GetPatent(ctx context.Context, in *GetPatentRequest, opts ...grpc.CallOption) (*Patent, error) {
+	out := new(Patent)
+	err := c.cc.Invoke(ctx, PatentSearch_GetPatent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *patentSearchClient) CreateIngestion(ctx context.Context, in *CreateIngestionRequest, opts ...grpc.CallOption) (*CreateIngestionResponse, error) {
+	out := new(CreateIngestionResponse)
+	err := c.cc.Invoke(ctx, PatentSearch_CreateIngestion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *patentSearchClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, PatentSearch_GetJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *patentSearchClient) WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (PatentSearch_WatchJobClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PatentSearch_ServiceDesc.Streams[0], PatentSearch_WatchJob_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &patentSearchWatchJobClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PatentSearch_WatchJobClient interface {
+	Recv() (*JobEvent, error)
+	grpc.ClientStream
+}
+
+type patentSearchWatchJobClient struct {
+	grpc.ClientStream
+}
+
+func (x *patentSearchWatchJobClient) Recv() (*JobEvent, error) {
+	m := new(JobEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PatentSearchServer is the server API for PatentSearch service.
+// All implementations must embed UnimplementedPatentSearchServer
+// for forward compatibility
+type PatentSearchServer interface {
+	// Search runs a query string search, like GET /api/v1/search. Requires the
+	// reader role.
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// GetPatent returns a patent by storage ID, like GET /api/v1/patents/{id}.
+	// Requires the reader role.
+	GetPatent(context.Context, *GetPatentRequest) (*Patent, error)
+	// CreateIngestion starts an ingestion job, like POST /api/v1/ingestions.
+	// Requires the ingestor role.
+	CreateIngestion(context.Context, *CreateIngestionRequest) (*CreateIngestionResponse, error)
+	// GetJob returns an ingestion job, like GET /api/v1/jobs/{id}. Requires the
+	// ingestor role.
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// WatchJob streams the state changes and file counters of an ingestion job
+	// until it finishes, like GET /api/v1/jobs/{id}/events. The first event is
+	// the current state of the job. Requires the ingestor role.
+	WatchJob(*WatchJobRequest, PatentSearch_WatchJobServer) error
+	mustEmbedUnimplementedPatentSearchServer()
+}
+
+// UnimplementedPatentSearchServer must be embedded to have forward compatible implementations.
+type UnimplementedPatentSearchServer struct {
+}
+
+func (UnimplementedPatentSearchServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedPatentSearchServer) GetPatent(context.Context, *GetPatentRequest) (*Patent, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPatent not implemented")
+}
+func (UnimplementedPatentSearchServer) CreateIngestion(context.Context, *CreateIngestionRequest) (*CreateIngestionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateIngestion not implemented")
+}
+func (UnimplementedPatentSearchServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedPatentSearchServer) WatchJob(*WatchJobRequest, PatentSearch_WatchJobServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchJob not implemented")
+}
+func (UnimplementedPatentSearchServer) mustEmbedUnimplementedPatentSearchServer() {}
+
+// UnsafePatentSearchServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PatentSearchServer will
+// result in compilation errors.
+type UnsafePatentSearchServer interface {
+	mustEmbedUnimplementedPatentSearchServer()
+}
+
+func RegisterPatentSearchServer(s grpc.ServiceRegistrar, srv PatentSearchServer) {
+	s.RegisterService(&PatentSearch_ServiceDesc, srv)
+}
+
+func _PatentSearch_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PatentSearchServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PatentSearch_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PatentSearchServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PatentSearch_GetPatent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPatentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PatentSearchServer).GetPatent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PatentSearch_GetPatent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PatentSearchServer).GetPatent(ctx, req.(*GetPatentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PatentSearch_CreateIngestion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateIngestionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PatentSearchServer).CreateIngestion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PatentSearch_CreateIngestion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PatentSearchServer).CreateIngestion(ctx, req.(*CreateIngestionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PatentSearch_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PatentSearchServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PatentSearch_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PatentSearchServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PatentSearch_WatchJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PatentSearchServer).WatchJob(m, &patentSearchWatchJobServer{stream})
+}
+
+type PatentSearch_WatchJobServer interface {
+	Send(*JobEvent) error
+	grpc.ServerStream
+}
+
+type patentSearchWatchJobServer struct {
+	grpc.ServerStream
+}
+
+func (x *patentSearchWatchJobServer) Send(m *JobEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// PatentSearch_ServiceDesc is the grpc.ServiceDesc for PatentSearch service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PatentSearch_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "search.v1.PatentSearch",
+	HandlerType: (*PatentSearchServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _PatentSearch_Search_Handler,
+		},
+		{
+			MethodName: "GetPatent",
+			Handler:    _PatentSearch_GetPatent_Handler,
+		},
+		{
+			MethodName: "CreateIngestion",
+			Handler:    _PatentSearch_CreateIngestion_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _PatentSearch_GetJob_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchJob",
+			Handler:       _PatentSearch_WatchJob_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "search.proto",
+}
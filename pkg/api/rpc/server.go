@@ -0,0 +1,326 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/analytics"
+	"github.com/avyukth/search-app/pkg/api/rpc/searchpb"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/downloader"
+	"github.com/avyukth/search-app/pkg/events"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/avyukth/search-app/pkg/ingest"
+	"github.com/avyukth/search-app/pkg/queue"
+	"github.com/avyukth/search-app/pkg/ratelimit"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchKeepalive is how often an idle connection is probed, so that clients
+// gone in the middle of a WatchJob stream are noticed.
+const watchKeepalive = 15 * time.Second
+
+// Server implements the PatentSearch gRPC service on the same search engine,
+// database, task queue and event broker as the HTTP API.
+type Server struct {
+	searchpb.UnimplementedPatentSearchServer
+
+	db           *mongo.Database
+	searchEngine indexer.SearchBackend
+	queryLog     *analytics.Recorder
+	ingestions   *ingest.Submitter
+	broker       *events.Broker
+}
+
+func NewServer(db *mongo.Database, searchEngine indexer.SearchBackend, ingestions *ingest.Submitter, queryLog *analytics.Recorder, broker *events.Broker) *Server {
+	return &Server{
+		db:           db,
+		searchEngine: searchEngine,
+		queryLog:     queryLog,
+		ingestions:   ingestions,
+		broker:       broker,
+	}
+}
+
+// NewGRPCServer returns a gRPC server serving srv. When authEnabled is set,
+// every call requires an API key with the role of the matching HTTP route.
+// Calls count against the same limits as the matching HTTP routes.
+func NewGRPCServer(srv *Server, authEnabled bool, limits *ratelimit.Limits) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: watchKeepalive}),
+	}
+	limiter := &rateLimiter{limits: limits}
+	if authEnabled {
+		a := &authenticator{db: srv.db}
+		opts = append(opts, grpc.ChainUnaryInterceptor(a.unary, limiter.unary), grpc.StreamInterceptor(a.stream))
+	} else {
+		opts = append(opts, grpc.UnaryInterceptor(limiter.unary))
+	}
+	server := grpc.NewServer(opts...)
+	searchpb.RegisterPatentSearchServer(server, srv)
+	return server
+}
+
+// Search runs a query string search. Queries matching nothing get spelling
+// corrections and, with autocorrect, are rerun with the suggested query.
+func (s *Server) Search(ctx context.Context, req *searchpb.SearchRequest) (*searchpb.SearchResponse, error) {
+	start := time.Now()
+	if req.Query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+
+	results, err := s.searchEngine.SearchAndRetrievePatents(ctx, req.Query)
+	if err != nil {
+		return nil, searchError(err)
+	}
+	response := &searchpb.SearchResponse{Query: req.Query, Results: patentsMessage(results)}
+	if len(results) == 0 {
+		spelling, err := s.searchEngine.SuggestSpelling(req.Query)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		response.Spelling = spellingMessage(spelling)
+		if req.Autocorrect && spelling.DidYouMean != "" {
+			response.CorrectedQuery = spelling.DidYouMean
+			corrected, err := s.searchEngine.SearchAndRetrievePatents(ctx, response.CorrectedQuery)
+			if err != nil {
+				return nil, searchError(err)
+			}
+			response.Results = patentsMessage(corrected)
+		}
+	}
+
+	var filters map[string]string
+	if req.Autocorrect {
+		filters = map[string]string{"autocorrect": "true"}
+	}
+	s.recordSearch(ctx, searchpb.PatentSearch_Search_FullMethodName, req.Query, filters, len(results), start)
+	return response, nil
+}
+
+// recordSearch adds a completed search to the query log. The caller is the
+// ID of the API key, or the client IP when authentication is disabled.
+func (s *Server) recordSearch(ctx context.Context, method, query string, filters map[string]string, hits int, start time.Time) {
+	var caller string
+	if apiKey := keyFrom(ctx); apiKey != nil {
+		caller = apiKey.ID.Hex()
+	} else {
+		caller = peerIP(ctx)
+	}
+	s.queryLog.Record(mongo.QueryLog{
+		Endpoint:  "GRPC " + method,
+		Query:     analytics.NormalizeQuery(query),
+		Filters:   filters,
+		Hits:      hits,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Caller:    caller,
+	})
+}
+
+// searchError converts a failed search to InvalidArgument for queries
+// rejected as too expensive, and DeadlineExceeded for searches that ran out
+// of time.
+func searchError(err error) error {
+	var verr *indexer.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		return status.Error(codes.InvalidArgument, verr.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "search exceeded its time limit, narrow the query and retry")
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// GetPatent returns the normalized record of a patent by storage ID.
+func (s *Server) GetPatent(ctx context.Context, req *searchpb.GetPatentRequest) (*searchpb.Patent, error) {
+	if _, err := mongo.ParseStorageID(req.Id); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid patent id")
+	}
+	patent, err := s.db.RetrievePatent(req.Id)
+	if err != nil {
+		return nil, lookupError(err)
+	}
+	return patentMessage(patent), nil
+}
+
+// CreateIngestion starts an ingestion job. A request sent again with the same
+// idempotency key returns the job of the first request, which is shared with
+// the Idempotency-Key header of POST /api/v1/ingestions.
+func (s *Server) CreateIngestion(ctx context.Context, req *searchpb.CreateIngestionRequest) (*searchpb.CreateIngestionResponse, error) {
+	ingestion := &ingest.Request{
+		Type:    req.Type,
+		URL:     req.Url,
+		Path:    req.Path,
+		Options: ingest.Options{Force: req.Force},
+	}
+	if err := ingestion.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(req.IdempotencyKey) > ingest.MaxIdempotencyKeyLength {
+		return nil, status.Errorf(codes.InvalidArgument, "idempotency_key must be at most %d characters", ingest.MaxIdempotencyKeyLength)
+	}
+
+	var apiKeyID string
+	if apiKey := keyFrom(ctx); apiKey != nil {
+		apiKeyID = apiKey.ID.Hex()
+	}
+	result, err := s.ingestions.Submit(ctx, ingestion, apiKeyID, req.IdempotencyKey)
+	switch {
+	case err == nil:
+		return &searchpb.CreateIngestionResponse{JobId: result.JobID, Replayed: result.Replayed}, nil
+	case errors.Is(err, downloader.ErrLinkNotLive):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ingest.ErrAlreadyProcessed):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ingest.ErrKeyInProgress):
+		return nil, status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, ingest.ErrKeyReused):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, queue.ErrFull), errors.Is(err, queue.ErrStopped), errors.Is(err, queue.ErrDraining):
+		return nil, status.Error(codes.Unavailable, err.Error())
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+}
+
+// GetJob returns the state and counters of an ingestion job.
+func (s *Server) GetJob(ctx context.Context, req *searchpb.GetJobRequest) (*searchpb.Job, error) {
+	if !primitive.IsValidObjectID(req.Id) {
+		return nil, status.Error(codes.InvalidArgument, "invalid job id")
+	}
+	job, err := s.db.RetrieveJob(req.Id)
+	if err != nil {
+		return nil, lookupError(err)
+	}
+	return jobMessage(job), nil
+}
+
+// WatchJob streams the events of an ingestion job, starting with its current
+// state, and returns after the final event.
+func (s *Server) WatchJob(req *searchpb.WatchJobRequest, stream searchpb.PatentSearch_WatchJobServer) error {
+	if !primitive.IsValidObjectID(req.Id) {
+		return status.Error(codes.InvalidArgument, "invalid job id")
+	}
+	first, updates, cancel, err := s.broker.Watch(s.db, req.Id)
+	if err != nil {
+		return lookupError(err)
+	}
+	defer cancel()
+
+	if err := stream.Send(eventMessage(first)); err != nil || first.Final() {
+		return err
+	}
+	for {
+		select {
+		case event, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(eventMessage(event)); err != nil || event.Final() {
+				return err
+			}
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+}
+
+func lookupError(err error) error {
+	if errors.Is(err, mongo.ErrNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func patentsMessage(patents []mongo.Patent) []*searchpb.Patent {
+	messages := make([]*searchpb.Patent, len(patents))
+	for i := range patents {
+		messages[i] = patentMessage(&patents[i])
+	}
+	return messages
+}
+
+func patentMessage(patent *mongo.Patent) *searchpb.Patent {
+	inventors := make([]*searchpb.Inventor, len(patent.Inventors))
+	for i, inventor := range patent.Inventors {
+		inventors[i] = &searchpb.Inventor{FirstName: inventor.FirstName, LastName: inventor.LastName}
+	}
+	return &searchpb.Patent{
+		PatentTitle:     patent.PatentTitle,
+		PatentNumber:    patent.PatentNumber,
+		InventorNames:   patent.InventorNames,
+		Inventors:       inventors,
+		AssigneeName:    patent.AssigneeName,
+		ApplicationDate: patent.ApplicationDate,
+		IssueDate:       patent.IssueDate,
+		DesignClass:     patent.DesignClass,
+		PatentStorageId: patent.PatentStorageID,
+	}
+}
+
+func spellingMessage(spelling *indexer.SpellingResult) *searchpb.Spelling {
+	corrections := make([]*searchpb.SpellingCorrection, len(spelling.Corrections))
+	for i, correction := range spelling.Corrections {
+		candidates := make([]*searchpb.SpellingCandidate, len(correction.Candidates))
+		for j, candidate := range correction.Candidates {
+			candidates[j] = &searchpb.SpellingCandidate{Text: candidate.Text, Count: candidate.Count}
+		}
+		corrections[i] = &searchpb.SpellingCorrection{Term: correction.Term, Candidates: candidates}
+	}
+	return &searchpb.Spelling{Corrections: corrections, DidYouMean: spelling.DidYouMean}
+}
+
+func countersMessage(counters mongo.JobCounters) *searchpb.JobCounters {
+	return &searchpb.JobCounters{
+		FilesSeen:    counters.FilesSeen,
+		FilesParsed:  counters.FilesParsed,
+		FilesFailed:  counters.FilesFailed,
+		FilesIndexed: counters.FilesIndexed,
+	}
+}
+
+func jobMessage(job *mongo.Job) *searchpb.Job {
+	message := &searchpb.Job{
+		Id:        job.ID.Hex(),
+		Type:      job.Type,
+		Source:    job.Source,
+		State:     job.State,
+		Counters:  countersMessage(job.Counters),
+		Error:     job.Error,
+		CreatedAt: timestamppb.New(job.CreatedAt),
+		UpdatedAt: timestamppb.New(job.UpdatedAt),
+	}
+	if job.FinishedAt != nil {
+		message.FinishedAt = timestamppb.New(*job.FinishedAt)
+	}
+	return message
+}
+
+func eventMessage(event events.Event) *searchpb.JobEvent {
+	return &searchpb.JobEvent{
+		Type:     event.Type,
+		JobId:    event.JobID,
+		State:    event.State,
+		Counters: countersMessage(event.Counters),
+		Error:    event.Error,
+		Time:     timestamppb.New(event.Time),
+	}
+}
+
+// Serve serves server on port until it is stopped.
+func Serve(server *grpc.Server, port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return server.Serve(listener)
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Roles, from least to most privileged. Each role may do everything the roles
+// before it may.
+const (
+	// RoleReader may search and read patents.
+	RoleReader = "reader"
+	// RoleIngestor may also start ingestions and follow their jobs.
+	RoleIngestor = "ingestor"
+	// RoleAdmin may also use the admin and analytics routes.
+	RoleAdmin = "admin"
+)
+
+// Roles lists the roles from least to most privileged.
+var Roles = []string{RoleReader, RoleIngestor, RoleAdmin}
+
+// keyPrefix starts every API key, so that leaked keys are easy to recognize.
+const keyPrefix = "psk_"
+
+// APIKeyHeader is the header carrying an API key, as an alternative to an
+// "Authorization: Bearer" header.
+const APIKeyHeader = "X-API-Key"
+
+// localsKey stores the authenticated key in the request locals.
+const localsKey = "apiKey"
+
+// IsRole reports whether role is one of Roles.
+func IsRole(role string) bool {
+	return rank(role) >= 0
+}
+
+func rank(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// GenerateKey returns a new random API key and its record, which holds the
+// hash of the key rather than the key itself.
+func GenerateKey(name, role string) (string, *mongo.APIKey, error) {
+	if !IsRole(role) {
+		return "", nil, fmt.Errorf("unknown role %q, expected one of %v", role, Roles)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("generating API key: %w", err)
+	}
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, &mongo.APIKey{
+		Name:   name,
+		Role:   role,
+		Prefix: key[:len(keyPrefix)+6],
+		Hash:   HashKey(key),
+	}, nil
+}
+
+// HashKey returns the hash under which a key is stored. Keys are random, so a
+// plain SHA-256 is enough to make the stored hashes useless to an attacker.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate rejects requests without a valid API key with 401 and stores
+// the key of the others for Require and KeyFrom.
+func Authenticate(db *mongo.Database) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := requestKey(c)
+		if key == "" {
+			return unauthorized(c, "API key required")
+		}
+		apiKey, err := db.RetrieveAPIKeyByHash(HashKey(key))
+		if errors.Is(err, mongo.ErrNotFound) {
+			return unauthorized(c, "invalid API key")
+		}
+		if err != nil {
+			return fmt.Errorf("error authenticating API key: %w", err)
+		}
+		c.Locals(localsKey, apiKey)
+		return c.Next()
+	}
+}
+
+// Require rejects requests whose API key has a role below role with 403.
+// Requests are let through when authentication is disabled, that is when
+// Authenticate did not run.
+func Require(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		apiKey, ok := c.Locals(localsKey).(*mongo.APIKey)
+		if !ok {
+			return c.Next()
+		}
+		if !Allows(apiKey, role) {
+			return problem.Newf(fiber.StatusForbidden, "this route requires the %s role", role)
+		}
+		return c.Next()
+	}
+}
+
+// Allows reports whether apiKey has role or a more privileged one.
+func Allows(apiKey *mongo.APIKey, role string) bool {
+	return rank(apiKey.Role) >= rank(role)
+}
+
+// KeyFrom returns the API key of an authenticated request, or nil.
+func KeyFrom(c *fiber.Ctx) *mongo.APIKey {
+	apiKey, _ := c.Locals(localsKey).(*mongo.APIKey)
+	return apiKey
+}
+
+func requestKey(c *fiber.Ctx) string {
+	if key := c.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	scheme, token, found := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func unauthorized(c *fiber.Ctx, message string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="search"`)
+	return problem.New(fiber.StatusUnauthorized, message)
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+)
+
+// CachedBackend serves repeated searches from a Cache. Every change to the
+// index bumps the cache generation, so results never outlive an ingest;
+// ingestion indexes patents in batches, bumping it once per batch. Cache
+// failures are logged and the search falls through to the backend.
+type CachedBackend struct {
+	indexer.SearchBackend
+	cache Cache
+}
+
+func NewCachedBackend(backend indexer.SearchBackend, cache Cache) *CachedBackend {
+	return &CachedBackend{
+		SearchBackend: backend,
+		cache:         cache,
+	}
+}
+
+func (b *CachedBackend) IndexPatent(patent *mongo.Patent) error {
+	if err := b.SearchBackend.IndexPatent(patent); err != nil {
+		return err
+	}
+	b.invalidate()
+	return nil
+}
+
+func (b *CachedBackend) IndexPatents(patents []*mongo.Patent) error {
+	if err := b.SearchBackend.IndexPatents(patents); err != nil {
+		return err
+	}
+	b.invalidate()
+	return nil
+}
+
+func (b *CachedBackend) DeletePatent(patentID string) error {
+	if err := b.SearchBackend.DeletePatent(patentID); err != nil {
+		return err
+	}
+	b.invalidate()
+	return nil
+}
+
+func (b *CachedBackend) Restore(srcDir string) error {
+	if err := b.SearchBackend.Restore(srcDir); err != nil {
+		return err
+	}
+	b.invalidate()
+	return nil
+}
+
+func (b *CachedBackend) SearchAndRetrievePatents(ctx context.Context, searchTerm string) ([]mongo.Patent, error) {
+	key := cacheKey("query", NormalizeQuery(searchTerm))
+	var patents []mongo.Patent
+	generation, hit := b.load(ctx, key, &patents)
+	if hit {
+		return patents, nil
+	}
+
+	patents, err := b.SearchBackend.SearchAndRetrievePatents(ctx, searchTerm)
+	if err != nil {
+		return nil, err
+	}
+	b.store(ctx, generation, key, patents)
+	return patents, nil
+}
+
+func (b *CachedBackend) Search(ctx context.Context, req *indexer.SearchRequest) (*indexer.SearchResult, error) {
+	// encoding/json sorts map keys, so equal requests encode identically.
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		return b.SearchBackend.Search(ctx, req)
+	}
+	key := cacheKey("dsl", string(encoded))
+	var result indexer.SearchResult
+	generation, hit := b.load(ctx, key, &result)
+	if hit {
+		return &result, nil
+	}
+
+	searchResult, err := b.SearchBackend.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	b.store(ctx, generation, key, searchResult)
+	return searchResult, nil
+}
+
+// NormalizeQuery collapses the whitespace of a query string. Case is kept,
+// since field names and operators are case sensitive.
+func NormalizeQuery(searchTerm string) string {
+	return strings.Join(strings.Fields(searchTerm), " ")
+}
+
+func cacheKey(kind, query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return kind + ":" + hex.EncodeToString(sum[:])
+}
+
+// load decodes a cached value into v. It returns the generation the value
+// belongs to, to be passed to store on a miss.
+func (b *CachedBackend) load(ctx context.Context, key string, v interface{}) (uint64, bool) {
+	generation, err := b.cache.Generation(ctx)
+	if err != nil {
+		log.Printf("Error reading cache generation: %v", err)
+		return 0, false
+	}
+	value, ok, err := b.cache.Get(ctx, generation, key)
+	if err != nil {
+		log.Printf("Error reading cached result: %v", err)
+		return generation, false
+	}
+	if !ok {
+		return generation, false
+	}
+	if err := json.Unmarshal(value, v); err != nil {
+		log.Printf("Error decoding cached result: %v", err)
+		return generation, false
+	}
+	return generation, true
+}
+
+func (b *CachedBackend) store(ctx context.Context, generation uint64, key string, v interface{}) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error encoding result for cache: %v", err)
+		return
+	}
+	if err := b.cache.Set(ctx, generation, key, value); err != nil {
+		log.Printf("Error caching result: %v", err)
+	}
+}
+
+// invalidate bumps the cache generation. It is not bound to a request, since
+// results cached before a change must never be served after it.
+func (b *CachedBackend) invalidate() {
+	if err := b.cache.BumpGeneration(context.Background()); err != nil {
+		log.Printf("Error invalidating search cache: %v", err)
+	}
+}
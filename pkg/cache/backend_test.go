@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+)
+
+// countingBackend counts the searches reaching the backend and the patents
+// indexed.
+type countingBackend struct {
+	indexer.SearchBackend
+	searches int
+	indexed  int
+}
+
+func (b *countingBackend) SearchAndRetrievePatents(ctx context.Context, searchTerm string) ([]mongo.Patent, error) {
+	b.searches++
+	return []mongo.Patent{{PatentTitle: searchTerm}}, nil
+}
+
+func (b *countingBackend) IndexPatent(patent *mongo.Patent) error {
+	b.indexed++
+	return nil
+}
+
+func (b *countingBackend) IndexPatents(patents []*mongo.Patent) error {
+	b.indexed += len(patents)
+	return nil
+}
+
+// generationCache counts the bumps of an LRUCache.
+type generationCache struct {
+	*LRUCache
+	bumps int
+}
+
+func (c *generationCache) BumpGeneration(ctx context.Context) error {
+	c.bumps++
+	return c.LRUCache.BumpGeneration(ctx)
+}
+
+func TestCachedBackend(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingBackend{}
+	cache := &generationCache{LRUCache: NewLRUCache(10, time.Minute)}
+	b := NewCachedBackend(backend, cache)
+
+	search := func(query string) {
+		t.Helper()
+		patents, err := b.SearchAndRetrievePatents(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(patents) != 1 || patents[0].PatentTitle != NormalizeQuery(query) {
+			t.Fatalf("SearchAndRetrievePatents(%q) = %+v", query, patents)
+		}
+	}
+
+	search("chair")
+	search("  chair ")
+	if backend.searches != 1 {
+		t.Fatalf("%d searches reached the backend, want 1 for equal normalized queries", backend.searches)
+	}
+
+	// A batch invalidates the cache once.
+	if err := b.IndexPatents([]*mongo.Patent{{}, {}, {}}); err != nil {
+		t.Fatal(err)
+	}
+	if cache.bumps != 1 {
+		t.Fatalf("indexing a batch bumped the generation %d times, want 1", cache.bumps)
+	}
+	search("chair")
+	if backend.searches != 2 {
+		t.Fatalf("%d searches reached the backend, want the cached result invalidated", backend.searches)
+	}
+	search("chair")
+	if backend.searches != 2 {
+		t.Fatalf("%d searches reached the backend, want the new result cached", backend.searches)
+	}
+}
+
+func TestCachedBackendRequestContext(t *testing.T) {
+	backend := &countingBackend{}
+	_, client := newMiniredis(t)
+	b := NewCachedBackend(backend, NewRedisCache(client, time.Minute))
+
+	// A cancelled request cannot reach Redis, and falls through to the
+	// backend.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 2; i++ {
+		if _, err := b.SearchAndRetrievePatents(ctx, "chair"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backend.searches != 2 {
+		t.Fatalf("%d searches reached the backend, want 2", backend.searches)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.SearchAndRetrievePatents(context.Background(), "chair"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backend.searches != 3 {
+		t.Fatalf("%d searches reached the backend, want the second one cached", backend.searches)
+	}
+}
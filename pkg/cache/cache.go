@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// NoBackend disables result caching.
+	NoBackend = "none"
+	// MemoryBackend keeps results in an in-process LRU.
+	MemoryBackend = "memory"
+	// RedisBackend keeps results in Redis, shared by every server instance.
+	RedisBackend = "redis"
+)
+
+// Cache stores encoded search results. Keys are scoped to an index
+// generation: bumping the generation makes every stored entry unreachable.
+type Cache interface {
+	Get(ctx context.Context, generation uint64, key string) ([]byte, bool, error)
+	Set(ctx context.Context, generation uint64, key string, value []byte) error
+	Generation(ctx context.Context) (uint64, error)
+	BumpGeneration(ctx context.Context) error
+}
+
+// ErrUnsupported is returned for an unknown cache backend.
+var ErrUnsupported = errors.New("unsupported cache backend")
+
+// NewCache creates the cache selected by cfg.CacheConfig.Backend. It returns a
+// nil Cache when caching is disabled.
+func NewCache(cfg *config.Config) (Cache, error) {
+	switch cfg.CacheConfig.Backend {
+	case NoBackend, "":
+		return nil, nil
+	case MemoryBackend:
+		return NewLRUCache(cfg.CacheConfig.Size, cfg.CacheConfig.TTL), nil
+	case RedisBackend:
+		client := redis.NewClient(&redis.Options{
+			Addr:         cfg.RedisConfig.Address,
+			Password:     cfg.RedisConfig.Password,
+			DB:           cfg.RedisConfig.DB,
+			DialTimeout:  cfg.RedisConfig.Timeout,
+			ReadTimeout:  cfg.RedisConfig.Timeout,
+			WriteTimeout: cfg.RedisConfig.Timeout,
+		})
+		return NewRedisCache(client, cfg.CacheConfig.TTL), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupported, cfg.CacheConfig.Backend)
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUCache(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// run stores and reads entries of a cache holding 2 entries and
+		// returns the keys to look up.
+		run  func(c *LRUCache) []string
+		want map[string]bool
+	}{
+		{
+			name: "hit",
+			run: func(c *LRUCache) []string {
+				c.Set(ctx, 0, "a", []byte("a"))
+				return []string{"a", "b"}
+			},
+			want: map[string]bool{"a": true, "b": false},
+		},
+		{
+			name: "evicts least recently used",
+			run: func(c *LRUCache) []string {
+				c.Set(ctx, 0, "a", []byte("a"))
+				c.Set(ctx, 0, "b", []byte("b"))
+				c.Get(ctx, 0, "a")
+				c.Set(ctx, 0, "c", []byte("c"))
+				return []string{"a", "b", "c"}
+			},
+			want: map[string]bool{"a": true, "b": false, "c": true},
+		},
+		{
+			name: "overwrite keeps size",
+			run: func(c *LRUCache) []string {
+				c.Set(ctx, 0, "a", []byte("a"))
+				c.Set(ctx, 0, "a", []byte("a"))
+				c.Set(ctx, 0, "b", []byte("b"))
+				return []string{"a", "b"}
+			},
+			want: map[string]bool{"a": true, "b": true},
+		},
+		{
+			name: "bump drops entries",
+			run: func(c *LRUCache) []string {
+				c.Set(ctx, 0, "a", []byte("a"))
+				c.BumpGeneration(ctx)
+				return []string{"a"}
+			},
+			want: map[string]bool{"a": false},
+		},
+		{
+			name: "stale set ignored",
+			run: func(c *LRUCache) []string {
+				c.BumpGeneration(ctx)
+				// A result computed before the bump.
+				c.Set(ctx, 0, "a", []byte("a"))
+				return []string{"a"}
+			},
+			want: map[string]bool{"a": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRUCache(2, 0)
+			for _, key := range tt.run(c) {
+				generation, _ := c.Generation(ctx)
+				value, ok, err := c.Get(ctx, generation, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok != tt.want[key] {
+					t.Errorf("Get(%q) hit = %v, want %v", key, ok, tt.want[key])
+				}
+				if ok && string(value) != key {
+					t.Errorf("Get(%q) = %q", key, value)
+				}
+			}
+		})
+	}
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(10, 10*time.Millisecond)
+	c.Set(ctx, 0, "a", []byte("a"))
+	if _, ok, _ := c.Get(ctx, 0, "a"); !ok {
+		t.Fatal("fresh entry missing")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok, _ := c.Get(ctx, 0, "a"); ok {
+		t.Fatal("expired entry returned")
+	}
+}
+
+func TestLRUCacheDisabled(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(0, 0)
+	c.Set(ctx, 0, "a", []byte("a"))
+	if _, ok, _ := c.Get(ctx, 0, "a"); ok {
+		t.Fatal("cache of size 0 returned an entry")
+	}
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix     = "search:"
+	redisGenerationKey = redisKeyPrefix + "generation"
+	// redisMaxTTL is the TTL of entries when none is configured, so that
+	// entries of old generations, which are never read again, expire.
+	redisMaxTTL = 24 * time.Hour
+)
+
+// RedisCache is a Cache stored in Redis. The generation is a Redis counter,
+// so an ingest on any server instance invalidates the results of all of them.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisCache creates a RedisCache. Entries expire after ttl, or after a
+// day when ttl is zero.
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	if ttl <= 0 {
+		ttl = redisMaxTTL
+	}
+	return &RedisCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (c *RedisCache) key(generation uint64, key string) string {
+	return fmt.Sprintf("%s%d:%s", redisKeyPrefix, generation, key)
+}
+
+func (c *RedisCache) Get(ctx context.Context, generation uint64, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, c.key(generation, key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("getting cached result: %w", err)
+	}
+	return value, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, generation uint64, key string, value []byte) error {
+	if err := c.client.Set(ctx, c.key(generation, key), value, c.ttl).Err(); err != nil {
+		return fmt.Errorf("caching result: %w", err)
+	}
+	return nil
+}
+
+func (c *RedisCache) Generation(ctx context.Context) (uint64, error) {
+	generation, err := c.client.Get(ctx, redisGenerationKey).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getting cache generation: %w", err)
+	}
+	return generation, nil
+}
+
+// BumpGeneration increments the generation. Entries of older generations are
+// left to expire.
+func (c *RedisCache) BumpGeneration(ctx context.Context) error {
+	if err := c.client.Incr(ctx, redisGenerationKey).Err(); err != nil {
+		return fmt.Errorf("bumping cache generation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newMiniredis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	_, client := newMiniredis(t)
+	// Two server instances sharing Redis.
+	first, second := NewRedisCache(client, time.Minute), NewRedisCache(client, time.Minute)
+
+	generation, err := first.Generation(ctx)
+	if err != nil || generation != 0 {
+		t.Fatalf("Generation() = %d, %v, want 0", generation, err)
+	}
+	if err := first.Set(ctx, generation, "query:a", []byte("results")); err != nil {
+		t.Fatal(err)
+	}
+	value, ok, err := second.Get(ctx, generation, "query:a")
+	if err != nil || !ok || string(value) != "results" {
+		t.Fatalf("Get() = %q, %v, %v, want the stored results", value, ok, err)
+	}
+	if _, ok, _ := second.Get(ctx, generation, "query:b"); ok {
+		t.Fatal("Get() of a missing key hit")
+	}
+
+	// A bump on one instance invalidates the entries of both.
+	if err := second.BumpGeneration(ctx); err != nil {
+		t.Fatal(err)
+	}
+	generation, err = first.Generation(ctx)
+	if err != nil || generation != 1 {
+		t.Fatalf("Generation() after bump = %d, %v, want 1", generation, err)
+	}
+	if _, ok, _ := first.Get(ctx, generation, "query:a"); ok {
+		t.Fatal("entry of the previous generation returned")
+	}
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		wantTTL time.Duration
+	}{
+		{"configured", time.Minute, time.Minute},
+		{"zero", 0, redisMaxTTL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server, client := newMiniredis(t)
+			c := NewRedisCache(client, tt.ttl)
+			if err := c.Set(ctx, 3, "query:a", []byte("results")); err != nil {
+				t.Fatal(err)
+			}
+			key := c.key(3, "query:a")
+			if got := server.TTL(key); got != tt.wantTTL {
+				t.Fatalf("TTL = %v, want %v", got, tt.wantTTL)
+			}
+
+			server.FastForward(tt.wantTTL)
+			if _, ok, _ := c.Get(ctx, 3, "query:a"); ok {
+				t.Fatal("expired entry returned")
+			}
+		})
+	}
+}
+
+func TestRedisCacheUnavailable(t *testing.T) {
+	ctx := context.Background()
+	server, client := newMiniredis(t)
+	c := NewRedisCache(client, time.Minute)
+	server.Close()
+
+	if _, err := c.Generation(ctx); err == nil {
+		t.Error("Generation() succeeded with Redis down")
+	}
+	if _, _, err := c.Get(ctx, 0, "query:a"); err == nil {
+		t.Error("Get() succeeded with Redis down")
+	}
+}
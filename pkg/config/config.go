@@ -18,9 +18,21 @@ type MongoDBConfig struct {
 	ContainerName string
 
 	// Collection names
-	StorageCollectionName string
-	IndexCollectionName   string
-	LinkCollectionName    string
+	StorageCollectionName     string
+	IndexCollectionName       string
+	LinkCollectionName        string
+	SavedSearchCollectionName string
+	AlertCollectionName       string
+	QueryLogCollectionName    string
+	JobCollectionName         string
+	IdempotencyCollectionName string
+	APIKeyCollectionName      string
+	QuotaCollectionName       string
+
+	// QueryLogMaxBytes caps the size of the query log collection.
+	QueryLogMaxBytes int64
+	// IdempotencyKeyTTL is how long an Idempotency-Key is remembered.
+	IdempotencyKeyTTL time.Duration
 }
 
 // RedisConfig holds the configuration related to Redis.
@@ -32,16 +44,110 @@ type RedisConfig struct {
 	ContainerName string
 }
 
-// ServerConfig holds the configuration related to the Server.
+// ServerConfig holds the configuration related to the Server. GRPCPort is the
+// port of the gRPC API, 0 to disable it.
 type ServerConfig struct {
 	ServiceHost        string
 	ServicePort        int
+	GRPCPort           int
 	Server             string
 	IndexDirectory     string
 	DataStoreDirectory string
 	Storage            string
 	ServiceName        string
 	ServiceVersion     string
+	SearchBackend      string
+	SearchTimeout      time.Duration
+	SearchMaxClauses   int
+	// ExtractMaxBytes caps the total size of the files extracted from an
+	// archive. Zero disables the cap.
+	ExtractMaxBytes int64
+}
+
+// AlertConfig holds the configuration related to saved search alert delivery.
+// Failed deliveries are tried up to WebhookAttempts times, waiting
+// WebhookBackoff before the first retry and twice as long before each next
+// one. Alerts beyond QueueSize waiting for delivery are left undelivered.
+type AlertConfig struct {
+	WebhookURL      string
+	WebhookTimeout  time.Duration
+	WebhookAttempts int
+	WebhookBackoff  time.Duration
+	QueueSize       int
+}
+
+// QueueConfig holds the configuration of the ingestion task queue.
+type QueueConfig struct {
+	// TaskTimeout cancels a task running longer. Zero lets tasks run until
+	// they finish or are cancelled by an operator.
+	TaskTimeout time.Duration
+}
+
+// AnalyzerConfig holds the configuration of the text analysis used by new indexes.
+type AnalyzerConfig struct {
+	Stemming    bool
+	SynonymFile string
+}
+
+// CacheConfig holds the configuration of the search result cache.
+type CacheConfig struct {
+	Backend string
+	Size    int
+	TTL     time.Duration
+}
+
+// SnapshotConfig holds the configuration of index snapshots.
+type SnapshotConfig struct {
+	Directory string
+	// Restore names a snapshot to restore when the server starts.
+	Restore string
+}
+
+// UploadConfig holds the configuration of file uploads.
+type UploadConfig struct {
+	// Directory is where uploads are stored, relative to the storage directory.
+	Directory string
+	MaxBytes  int64
+}
+
+// AuthConfig holds the configuration of API key authentication.
+type AuthConfig struct {
+	// Enabled requires an API key on every route but the liveness check.
+	Enabled bool
+}
+
+// RateLimitPolicy limits the requests of each caller to a route group. Zero
+// values disable the corresponding limit.
+type RateLimitPolicy struct {
+	// PerMinute is the rate at which a caller's token bucket refills.
+	PerMinute int
+	// Burst is the size of the token bucket.
+	Burst int
+	// DailyQuota caps the requests of a caller per UTC day.
+	DailyQuota int64
+}
+
+// RateLimitConfig holds the rate limits of the search and ingestion routes.
+type RateLimitConfig struct {
+	Search    RateLimitPolicy
+	Ingestion RateLimitPolicy
+}
+
+// GraphQLConfig holds the limits of the queries accepted by the GraphQL
+// endpoint. MaxDepth is the deepest nesting of fields, and MaxCost the number
+// of fields resolved, counting a field once per item of the lists it is
+// nested in.
+type GraphQLConfig struct {
+	MaxDepth int
+	MaxCost  int
+}
+
+// HealthConfig holds the configuration of the health checks. CheckTimeout
+// bounds each dependency check, and the disk check fails when less than
+// MinFreeBytes are free under the storage directory.
+type HealthConfig struct {
+	CheckTimeout time.Duration
+	MinFreeBytes int64
 }
 
 // Config holds all configuration for our program.
@@ -49,6 +155,16 @@ type Config struct {
 	MongoDBConfig
 	RedisConfig
 	ServerConfig
+	AlertConfig
+	QueueConfig
+	AnalyzerConfig
+	CacheConfig
+	SnapshotConfig
+	UploadConfig
+	AuthConfig
+	RateLimitConfig
+	GraphQLConfig
+	HealthConfig
 }
 
 // LoadConfig loads configuration from environment variables.
@@ -69,6 +185,15 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("STORAGE_COLLECTION_NAME", "storage")
 	viper.SetDefault("INDEX_COLLECTION_NAME", "index")
 	viper.SetDefault("LINK_COLLECTION_NAME", "link")
+	viper.SetDefault("SAVED_SEARCH_COLLECTION_NAME", "savedSearch")
+	viper.SetDefault("ALERT_COLLECTION_NAME", "alert")
+	viper.SetDefault("QUERY_LOG_COLLECTION_NAME", "queryLog")
+	viper.SetDefault("JOB_COLLECTION_NAME", "job")
+	viper.SetDefault("IDEMPOTENCY_COLLECTION_NAME", "idempotencyKey")
+	viper.SetDefault("API_KEY_COLLECTION_NAME", "apiKey")
+	viper.SetDefault("QUOTA_COLLECTION_NAME", "quota")
+	viper.SetDefault("QUERY_LOG_MAX_BYTES", 100<<20)
+	viper.SetDefault("IDEMPOTENCY_KEY_TTL", 86400) // Assuming this is in seconds
 
 	// Set defaults for RedisConfig
 	viper.SetDefault("REDIS_PASSWORD", "")
@@ -78,26 +203,86 @@ func LoadConfig() (*Config, error) {
 
 	// Set defaults for ServerConfig
 	viper.SetDefault("SERVER_PORT", 40051)
+	viper.SetDefault("GRPC_PORT", 40061)
 	viper.SetDefault("SERVER_HOST", "localhost")
 	viper.SetDefault("INDEX_DIRECTORY", "index")
 	viper.SetDefault("SERVER_DATA_STORE_DIRECTORY", "data")
 	viper.SetDefault("STORAGE_DIRECTORY", "local")
 	viper.SetDefault("SERVICE_NAME", "search")
 	viper.SetDefault("SERVICE_VERSION", "1.0.0")
+	viper.SetDefault("SEARCH_BACKEND", "bleve")
+	viper.SetDefault("SEARCH_TIMEOUT", 5) // Assuming this is in seconds
+	viper.SetDefault("SEARCH_MAX_CLAUSES", 64)
+	viper.SetDefault("EXTRACT_MAX_BYTES", 10<<30)
+
+	// Set defaults for AlertConfig
+	viper.SetDefault("ALERT_WEBHOOK_URL", "")
+	viper.SetDefault("ALERT_WEBHOOK_TIMEOUT", 10) // Assuming this is in seconds
+	viper.SetDefault("ALERT_WEBHOOK_ATTEMPTS", 5)
+	viper.SetDefault("ALERT_WEBHOOK_BACKOFF", 2) // Assuming this is in seconds
+	viper.SetDefault("ALERT_QUEUE_SIZE", 1000)
+
+	// Set defaults for QueueConfig
+	viper.SetDefault("QUEUE_TASK_TIMEOUT", 0) // Assuming this is in seconds
+
+	// Set defaults for AnalyzerConfig
+	viper.SetDefault("ANALYZER_STEMMING", true)
+	viper.SetDefault("ANALYZER_SYNONYM_FILE", "")
+
+	// Set defaults for CacheConfig
+	viper.SetDefault("CACHE_BACKEND", "memory")
+	viper.SetDefault("CACHE_SIZE", 1000)
+	viper.SetDefault("CACHE_TTL", 300) // Assuming this is in seconds
+
+	// Set defaults for SnapshotConfig
+	viper.SetDefault("SNAPSHOT_DIRECTORY", "snapshots")
+	viper.SetDefault("SNAPSHOT_RESTORE", "")
+
+	// Set defaults for UploadConfig
+	viper.SetDefault("UPLOAD_DIRECTORY", "uploads")
+	viper.SetDefault("UPLOAD_MAX_BYTES", 2<<30)
+
+	// Set defaults for AuthConfig
+	viper.SetDefault("AUTH_ENABLED", true)
+
+	// Set defaults for RateLimitConfig
+	viper.SetDefault("RATE_LIMIT_SEARCH_PER_MINUTE", 600)
+	viper.SetDefault("RATE_LIMIT_SEARCH_BURST", 60)
+	viper.SetDefault("RATE_LIMIT_SEARCH_DAILY_QUOTA", 100000)
+	viper.SetDefault("RATE_LIMIT_INGESTION_PER_MINUTE", 10)
+	viper.SetDefault("RATE_LIMIT_INGESTION_BURST", 5)
+	viper.SetDefault("RATE_LIMIT_INGESTION_DAILY_QUOTA", 500)
+
+	// Set defaults for GraphQLConfig
+	viper.SetDefault("GRAPHQL_MAX_DEPTH", 8)
+	viper.SetDefault("GRAPHQL_MAX_COST", 1000)
+
+	// Set defaults for HealthConfig
+	viper.SetDefault("HEALTH_CHECK_TIMEOUT", 2) // Assuming this is in seconds
+	viper.SetDefault("HEALTH_MIN_FREE_BYTES", 1<<30)
 
 	return &Config{
 		MongoDBConfig: MongoDBConfig{
-			Host:                  viper.GetString("MONGO_HOST"),
-			Port:                  viper.GetInt("MONGO_PORT"),
-			Username:              viper.GetString("MONGODB_USERNAME"),
-			Password:              viper.GetString("MONGODB_PASSWORD"),
-			Database:              viper.GetString("MONGO_DATABASE"),
-			MaxPoolSize:           viper.GetUint64("MONGO_MAX_POOL_SIZE"),
-			URI:                   getMongoUri(),
-			ContainerName:         viper.GetString("MONGO_CONTAINER_NAME"),
-			StorageCollectionName: viper.GetString("STORAGE_COLLECTION_NAME"),
-			IndexCollectionName:   viper.GetString("INDEX_COLLECTION_NAME"),
-			LinkCollectionName:    viper.GetString("LINK_COLLECTION_NAME"),
+			Host:                      viper.GetString("MONGO_HOST"),
+			Port:                      viper.GetInt("MONGO_PORT"),
+			Username:                  viper.GetString("MONGODB_USERNAME"),
+			Password:                  viper.GetString("MONGODB_PASSWORD"),
+			Database:                  viper.GetString("MONGO_DATABASE"),
+			MaxPoolSize:               viper.GetUint64("MONGO_MAX_POOL_SIZE"),
+			URI:                       getMongoUri(),
+			ContainerName:             viper.GetString("MONGO_CONTAINER_NAME"),
+			StorageCollectionName:     viper.GetString("STORAGE_COLLECTION_NAME"),
+			IndexCollectionName:       viper.GetString("INDEX_COLLECTION_NAME"),
+			LinkCollectionName:        viper.GetString("LINK_COLLECTION_NAME"),
+			SavedSearchCollectionName: viper.GetString("SAVED_SEARCH_COLLECTION_NAME"),
+			AlertCollectionName:       viper.GetString("ALERT_COLLECTION_NAME"),
+			QueryLogCollectionName:    viper.GetString("QUERY_LOG_COLLECTION_NAME"),
+			JobCollectionName:         viper.GetString("JOB_COLLECTION_NAME"),
+			IdempotencyCollectionName: viper.GetString("IDEMPOTENCY_COLLECTION_NAME"),
+			APIKeyCollectionName:      viper.GetString("API_KEY_COLLECTION_NAME"),
+			QuotaCollectionName:       viper.GetString("QUOTA_COLLECTION_NAME"),
+			QueryLogMaxBytes:          viper.GetInt64("QUERY_LOG_MAX_BYTES"),
+			IdempotencyKeyTTL:         time.Duration(viper.GetInt("IDEMPOTENCY_KEY_TTL")) * time.Second,
 		},
 		RedisConfig: RedisConfig{
 			Password:      viper.GetString("REDIS_PASSWORD"),
@@ -109,12 +294,67 @@ func LoadConfig() (*Config, error) {
 		ServerConfig: ServerConfig{
 			ServiceHost:        viper.GetString("SERVER_HOST"),
 			ServicePort:        viper.GetInt("SERVER_PORT"),
+			GRPCPort:           viper.GetInt("GRPC_PORT"),
 			Server:             viper.GetString("SERVER_HOST"),
 			IndexDirectory:     viper.GetString("INDEX_DIRECTORY"),
 			DataStoreDirectory: viper.GetString("DATA_STORE_DIRECTORY"),
 			Storage:            viper.GetString("STORAGE_DIRECTORY"),
 			ServiceName:        viper.GetString("SERVICE_NAME"),
 			ServiceVersion:     viper.GetString("SERVICE_VERSION"),
+			SearchBackend:      viper.GetString("SEARCH_BACKEND"),
+			SearchTimeout:      time.Duration(viper.GetInt("SEARCH_TIMEOUT")) * time.Second,
+			SearchMaxClauses:   viper.GetInt("SEARCH_MAX_CLAUSES"),
+			ExtractMaxBytes:    viper.GetInt64("EXTRACT_MAX_BYTES"),
+		},
+		AlertConfig: AlertConfig{
+			WebhookURL:      viper.GetString("ALERT_WEBHOOK_URL"),
+			WebhookTimeout:  time.Duration(viper.GetInt("ALERT_WEBHOOK_TIMEOUT")) * time.Second,
+			WebhookAttempts: viper.GetInt("ALERT_WEBHOOK_ATTEMPTS"),
+			WebhookBackoff:  time.Duration(viper.GetInt("ALERT_WEBHOOK_BACKOFF")) * time.Second,
+			QueueSize:       viper.GetInt("ALERT_QUEUE_SIZE"),
+		},
+		QueueConfig: QueueConfig{
+			TaskTimeout: time.Duration(viper.GetInt("QUEUE_TASK_TIMEOUT")) * time.Second,
+		},
+		AnalyzerConfig: AnalyzerConfig{
+			Stemming:    viper.GetBool("ANALYZER_STEMMING"),
+			SynonymFile: viper.GetString("ANALYZER_SYNONYM_FILE"),
+		},
+		CacheConfig: CacheConfig{
+			Backend: viper.GetString("CACHE_BACKEND"),
+			Size:    viper.GetInt("CACHE_SIZE"),
+			TTL:     time.Duration(viper.GetInt("CACHE_TTL")) * time.Second,
+		},
+		SnapshotConfig: SnapshotConfig{
+			Directory: viper.GetString("SNAPSHOT_DIRECTORY"),
+			Restore:   viper.GetString("SNAPSHOT_RESTORE"),
+		},
+		UploadConfig: UploadConfig{
+			Directory: viper.GetString("UPLOAD_DIRECTORY"),
+			MaxBytes:  viper.GetInt64("UPLOAD_MAX_BYTES"),
+		},
+		AuthConfig: AuthConfig{
+			Enabled: viper.GetBool("AUTH_ENABLED"),
+		},
+		RateLimitConfig: RateLimitConfig{
+			Search: RateLimitPolicy{
+				PerMinute:  viper.GetInt("RATE_LIMIT_SEARCH_PER_MINUTE"),
+				Burst:      viper.GetInt("RATE_LIMIT_SEARCH_BURST"),
+				DailyQuota: viper.GetInt64("RATE_LIMIT_SEARCH_DAILY_QUOTA"),
+			},
+			Ingestion: RateLimitPolicy{
+				PerMinute:  viper.GetInt("RATE_LIMIT_INGESTION_PER_MINUTE"),
+				Burst:      viper.GetInt("RATE_LIMIT_INGESTION_BURST"),
+				DailyQuota: viper.GetInt64("RATE_LIMIT_INGESTION_DAILY_QUOTA"),
+			},
+		},
+		GraphQLConfig: GraphQLConfig{
+			MaxDepth: viper.GetInt("GRAPHQL_MAX_DEPTH"),
+			MaxCost:  viper.GetInt("GRAPHQL_MAX_COST"),
+		},
+		HealthConfig: HealthConfig{
+			CheckTimeout: time.Duration(viper.GetInt("HEALTH_CHECK_TIMEOUT")) * time.Second,
+			MinFreeBytes: viper.GetInt64("HEALTH_MIN_FREE_BYTES"),
 		},
 	}, nil
 }
@@ -0,0 +1,181 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAlreadyAlerted is returned by StoreAlert when the saved search already
+// has an alert for the patent, for example because it was indexed again.
+var ErrAlreadyAlerted = errors.New("alert already recorded")
+
+// EnsureAlertIndexes creates the index that keeps a single alert per saved
+// search and patent.
+func (db *Database) EnsureAlertIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.AlertCollectionName)
+
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "savedSearchID", Value: 1}, {Key: "patentStorageID", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return fmt.Errorf("error creating alert index: %v", err)
+	}
+	return nil
+}
+
+// ownerFilter matches the saved searches and alerts of an API key. Documents
+// stored before they were scoped to the caller have no apiKeyID and belong to
+// the callers of a server without authentication.
+func ownerFilter(apiKeyID string) bson.M {
+	if apiKeyID == "" {
+		return bson.M{"apiKeyID": bson.M{"$in": bson.A{"", nil}}}
+	}
+	return bson.M{"apiKeyID": apiKeyID}
+}
+
+// StoreSavedSearch inserts a saved search and returns its ID.
+func (db *Database) StoreSavedSearch(search *SavedSearch) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.SavedSearchCollectionName)
+
+	search.ID = primitive.NewObjectID()
+	search.CreatedAt = time.Now()
+	if _, err := collection.InsertOne(ctx, search); err != nil {
+		return "", fmt.Errorf("error storing saved search to MongoDB: %v", err)
+	}
+
+	return search.ID.Hex(), nil
+}
+
+// ListSavedSearches returns the saved searches of an API key, oldest first.
+func (db *Database) ListSavedSearches(apiKeyID string) ([]SavedSearch, error) {
+	return db.findSavedSearches(ownerFilter(apiKeyID))
+}
+
+// AllSavedSearches returns the saved searches of every API key, oldest first.
+func (db *Database) AllSavedSearches() ([]SavedSearch, error) {
+	return db.findSavedSearches(bson.M{})
+}
+
+func (db *Database) findSavedSearches(filter bson.M) ([]SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.SavedSearchCollectionName)
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("error listing saved searches from MongoDB: %v", err)
+	}
+
+	searches := []SavedSearch{}
+	if err := cursor.All(ctx, &searches); err != nil {
+		return nil, fmt.Errorf("error decoding saved searches: %v", err)
+	}
+	return searches, nil
+}
+
+// DeleteSavedSearch removes a saved search of an API key. It returns
+// ErrNotFound if the API key has no saved search with the given ID.
+func (db *Database) DeleteSavedSearch(apiKeyID, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.SavedSearchCollectionName)
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("error converting string ID to ObjectID: %v", err)
+	}
+
+	filter := ownerFilter(apiKeyID)
+	filter["_id"] = objID
+	result, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("error deleting saved search from MongoDB: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("no saved search found with ID %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// StoreAlert inserts an undelivered alert and returns its ID. It returns
+// ErrAlreadyAlerted if the saved search already has an alert for the patent.
+func (db *Database) StoreAlert(alert *Alert) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.AlertCollectionName)
+
+	alert.ID = primitive.NewObjectID()
+	alert.CreatedAt = time.Now()
+	alert.UpdatedAt = alert.CreatedAt
+	if _, err := collection.InsertOne(ctx, alert); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", ErrAlreadyAlerted
+		}
+		return "", fmt.Errorf("error storing alert to MongoDB: %v", err)
+	}
+
+	return alert.ID.Hex(), nil
+}
+
+// MarkAlertDelivered records the outcome of delivering an alert. A nil
+// deliveryErr marks the alert as delivered.
+func (db *Database) MarkAlertDelivered(id string, deliveryErr error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.AlertCollectionName)
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("error converting string ID to ObjectID: %v", err)
+	}
+
+	update := bson.M{"delivered": deliveryErr == nil, "deliveryError": "", "updatedAt": time.Now()}
+	if deliveryErr != nil {
+		update["deliveryError"] = deliveryErr.Error()
+	}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": update}); err != nil {
+		return fmt.Errorf("error updating alert in MongoDB: %v", err)
+	}
+	return nil
+}
+
+// ListAlerts returns the most recent alerts of an API key, newest first. An
+// empty savedSearchID returns alerts for every saved search of the API key.
+func (db *Database) ListAlerts(apiKeyID, savedSearchID string, limit int64) ([]Alert, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.AlertCollectionName)
+
+	filter := ownerFilter(apiKeyID)
+	if savedSearchID != "" {
+		objID, err := primitive.ObjectIDFromHex(savedSearchID)
+		if err != nil {
+			return nil, fmt.Errorf("error converting string ID to ObjectID: %v", err)
+		}
+		filter["savedSearchID"] = objID
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error listing alerts from MongoDB: %v", err)
+	}
+
+	alerts := []Alert{}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		return nil, fmt.Errorf("error decoding alerts: %v", err)
+	}
+	return alerts, nil
+}
@@ -0,0 +1,123 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureAPIKeys creates the unique index on the hash of API keys.
+func (db *Database) EnsureAPIKeys() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.APIKeyCollectionName)
+
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return fmt.Errorf("error creating API key index: %v", err)
+	}
+	return nil
+}
+
+// StoreAPIKey inserts an API key and returns its ID.
+func (db *Database) StoreAPIKey(key *APIKey) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.APIKeyCollectionName)
+
+	key.ID = primitive.NewObjectID()
+	key.CreatedAt = time.Now()
+	if _, err := collection.InsertOne(ctx, key); err != nil {
+		return "", fmt.Errorf("error storing API key to MongoDB: %v", err)
+	}
+	return key.ID.Hex(), nil
+}
+
+// RetrieveAPIKeyByHash returns the API key with the given hash. It returns
+// ErrNotFound if there is none or if it was revoked.
+func (db *Database) RetrieveAPIKeyByHash(hash string) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.APIKeyCollectionName)
+
+	var key APIKey
+	err := collection.FindOne(ctx, bson.M{"hash": hash, "revokedAt": bson.M{"$exists": false}}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("no active API key found: %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("error retrieving API key from MongoDB: %v", err)
+	}
+	return &key, nil
+}
+
+// ListAPIKeys returns every API key, revoked ones included, oldest first.
+func (db *Database) ListAPIKeys() ([]APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.APIKeyCollectionName)
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("error listing API keys from MongoDB: %v", err)
+	}
+
+	keys := []APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("error decoding API keys: %v", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey disables an API key. It returns ErrNotFound if there is no
+// active key with that ID.
+func (db *Database) RevokeAPIKey(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.APIKeyCollectionName)
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("error converting string ID to ObjectID: %v", err)
+	}
+
+	filter := bson.M{"_id": objID, "revokedAt": bson.M{"$exists": false}}
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("error revoking API key in MongoDB: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("no active API key found with ID %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// SetAPIKeyQuotas replaces the daily quota overrides of an API key. It
+// returns ErrNotFound if there is no key with that ID.
+func (db *Database) SetAPIKeyQuotas(id string, quotas map[string]int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.APIKeyCollectionName)
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("error converting string ID to ObjectID: %v", err)
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": bson.M{"dailyQuotas": quotas}})
+	if err != nil {
+		return fmt.Errorf("error updating API key quotas in MongoDB: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("no API key found with ID %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
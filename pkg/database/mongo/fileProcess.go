@@ -44,6 +44,18 @@ func (db *Database) CheckAndSetLinkStatus(link string) (bool, error) {
 	return true, nil
 }
 
+// ReleaseLinkStatus forgets that a link was processed, so that a link whose
+// ingestion never started can be sent again.
+func (db *Database) ReleaseLinkStatus(link string) error {
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.LinkCollectionName)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	hash := sha256.Sum256([]byte(link))
+	_, err := collection.DeleteMany(ctx, bson.M{"linkHash": hex.EncodeToString(hash[:]), "status": "processed"})
+	return err
+}
+
 func (db *Database) IsLinkProcessed(ctx context.Context, id string) (bool, error) {
 	var result LinkStatus
 	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.LinkCollectionName)
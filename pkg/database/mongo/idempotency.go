@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIdempotencyKeys creates the index that expires idempotency keys after
+// IdempotencyKeyTTL and the one keeping the keys of each API key unique.
+func (db *Database) EnsureIdempotencyKeys() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.IdempotencyCollectionName)
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(db.Config.MongoDBConfig.IdempotencyKeyTTL.Seconds())),
+		},
+		{
+			Keys: bson.D{{Key: "apiKeyID", Value: 1}, {Key: "key", Value: 1}},
+			// Records stored before keys were scoped to the caller have no
+			// key field and expire with the TTL index.
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"key": bson.M{"$exists": true}}),
+		},
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexes); err != nil {
+		return fmt.Errorf("error creating idempotency key index: %v", err)
+	}
+	return nil
+}
+
+// ReserveIdempotencyKey stores record unless its API key already used its key.
+// It returns nil when the key was reserved and the existing record otherwise.
+func (db *Database) ReserveIdempotencyKey(record *IdempotencyKey) (*IdempotencyKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.IdempotencyCollectionName)
+
+	record.CreatedAt = time.Now()
+	_, err := collection.InsertOne(ctx, record)
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("error storing idempotency key to MongoDB: %v", err)
+	}
+
+	var existing IdempotencyKey
+	if err := collection.FindOne(ctx, bson.M{"apiKeyID": record.APIKeyID, "key": record.Key}).Decode(&existing); err != nil {
+		return nil, fmt.Errorf("error retrieving idempotency key from MongoDB: %v", err)
+	}
+	return &existing, nil
+}
+
+// DeleteIdempotencyKey releases a key of an API key, so that the request can
+// be retried.
+func (db *Database) DeleteIdempotencyKey(apiKeyID, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.IdempotencyCollectionName)
+
+	if _, err := collection.DeleteOne(ctx, bson.M{"apiKeyID": apiKeyID, "key": key}); err != nil {
+		return fmt.Errorf("error deleting idempotency key from MongoDB: %v", err)
+	}
+	return nil
+}
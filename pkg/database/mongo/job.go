@@ -0,0 +1,131 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StoreJob inserts a job in the queued state and returns its ID. A job without
+// an ID is given a new one.
+func (db *Database) StoreJob(job *Job) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.JobCollectionName)
+
+	if job.ID.IsZero() {
+		job.ID = primitive.NewObjectID()
+	}
+	job.State = JobQueued
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	if _, err := collection.InsertOne(ctx, job); err != nil {
+		return "", fmt.Errorf("error storing job to MongoDB: %v", err)
+	}
+
+	return job.ID.Hex(), nil
+}
+
+// UpdateJobState moves a job to state. Moving to JobDone or JobFailed also
+// records when the job finished; jobErr is recorded for failed jobs.
+func (db *Database) UpdateJobState(id, state string, jobErr error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.JobCollectionName)
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("error converting string ID to ObjectID: %v", err)
+	}
+
+	now := time.Now()
+	update := bson.M{"state": state, "updatedAt": now}
+	if state == JobDone || state == JobFailed {
+		update["finishedAt"] = now
+	}
+	if jobErr != nil {
+		update["error"] = jobErr.Error()
+	}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": update}); err != nil {
+		return fmt.Errorf("error updating job in MongoDB: %v", err)
+	}
+	return nil
+}
+
+// IncrementJobCounters adds delta to the counters of a job.
+func (db *Database) IncrementJobCounters(id string, delta JobCounters) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.JobCollectionName)
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("error converting string ID to ObjectID: %v", err)
+	}
+
+	update := bson.M{
+		"$inc": bson.M{
+			"counters.filesSeen":    delta.FilesSeen,
+			"counters.filesParsed":  delta.FilesParsed,
+			"counters.filesFailed":  delta.FilesFailed,
+			"counters.filesIndexed": delta.FilesIndexed,
+		},
+		"$set": bson.M{"updatedAt": time.Now()},
+	}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, update); err != nil {
+		return fmt.Errorf("error updating job counters in MongoDB: %v", err)
+	}
+	return nil
+}
+
+// RetrieveJob returns a job by ID. It returns ErrNotFound if there is none.
+func (db *Database) RetrieveJob(id string) (*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.JobCollectionName)
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("error converting string ID to ObjectID: %v", err)
+	}
+
+	var job Job
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("no job found with ID %s: %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("error retrieving job from MongoDB: %v", err)
+	}
+	return &job, nil
+}
+
+// ListJobs returns the most recent jobs, newest first. An empty state returns
+// jobs in every state.
+func (db *Database) ListJobs(state string, limit int64) ([]Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.JobCollectionName)
+
+	filter := bson.M{}
+	if state != "" {
+		filter["state"] = state
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error listing jobs from MongoDB: %v", err)
+	}
+
+	jobs := []Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, fmt.Errorf("error decoding jobs: %v", err)
+	}
+	return jobs, nil
+}
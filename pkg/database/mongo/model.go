@@ -18,14 +18,53 @@ type LinkStatus struct {
 }
 
 type Patent struct {
-	PatentTitle     string   `bson:"patentTitle"`
-	PatentNumber    string   `bson:"patentNumber"`
-	InventorNames   []string `bson:"inventorNames"`
-	AssigneeName    string   `bson:"assigneeName"`
-	ApplicationDate string   `bson:"applicationDate"`
-	IssueDate       string   `bson:"issueDate"`
-	DesignClass     string   `bson:"designClass,omitempty"`
-	PatentStorageID string   `bson:"patentStorageID"`
+	PatentTitle     string     `bson:"patentTitle"`
+	PatentNumber    string     `bson:"patentNumber"`
+	InventorNames   []string   `bson:"inventorNames"`
+	Inventors       []Inventor `bson:"inventors,omitempty"`
+	AssigneeName    string     `bson:"assigneeName"`
+	ApplicationDate string     `bson:"applicationDate"`
+	IssueDate       string     `bson:"issueDate"`
+	DesignClass     string     `bson:"designClass,omitempty"`
+	PatentStorageID string     `bson:"patentStorageID"`
+	// Classifications and Citations are only recorded for patents ingested
+	// since they were added.
+	Classifications []Classification `bson:"classifications,omitempty"`
+	Citations       []Citation       `bson:"citations,omitempty"`
+}
+
+// Inventor is an inventor name as it appears in the grant.
+type Inventor struct {
+	FirstName string `bson:"firstName"`
+	LastName  string `bson:"lastName"`
+}
+
+// Classification is a CPC classification of a patent. Main is set for the
+// main classification, the others being further classifications.
+type Classification struct {
+	Section   string `bson:"section"`
+	Class     string `bson:"class"`
+	Subclass  string `bson:"subclass"`
+	MainGroup string `bson:"mainGroup"`
+	Subgroup  string `bson:"subgroup"`
+	Main      bool   `bson:"main"`
+}
+
+// Symbol returns the classification in the usual CPC notation, such as
+// "A47C 7/02".
+func (c Classification) Symbol() string {
+	return c.Section + c.Class + c.Subclass + " " + c.MainGroup + "/" + c.Subgroup
+}
+
+// Citation is a patent cited by the examiner or the applicant.
+type Citation struct {
+	Country   string `bson:"country"`
+	DocNumber string `bson:"docNumber"`
+	Kind      string `bson:"kind,omitempty"`
+	Name      string `bson:"name,omitempty"`
+	Date      string `bson:"date,omitempty"`
+	// Category is "cited by examiner" or "cited by applicant".
+	Category string `bson:"category,omitempty"`
 }
 
 type Index struct {
@@ -33,6 +72,133 @@ type Index struct {
 	PatentObj Patent             `bson:"patentObj"`
 }
 
+// SavedSearch is a named query, with optional field filters, that is evaluated
+// against every newly indexed patent.
+type SavedSearch struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	// APIKeyID is the hex ID of the API key that saved the search, or empty
+	// when authentication is disabled. Callers only see their own searches.
+	APIKeyID  string            `bson:"apiKeyID" json:"apiKeyId"`
+	Name      string            `bson:"name" json:"name"`
+	Query     string            `bson:"query" json:"query"`
+	Filters   map[string]string `bson:"filters,omitempty" json:"filters,omitempty"`
+	CreatedAt time.Time         `bson:"createdAt" json:"createdAt"`
+}
+
+// Job states. A job moves forward through the states in this order and ends
+// as JobDone or JobFailed.
+const (
+	JobQueued      = "queued"
+	JobDownloading = "downloading"
+	JobExtracting  = "extracting"
+	JobParsing     = "parsing"
+	JobIndexing    = "indexing"
+	JobDone        = "done"
+	JobFailed      = "failed"
+)
+
+// JobCounters tracks the XML files handled by an ingestion job.
+type JobCounters struct {
+	FilesSeen    int64 `bson:"filesSeen" json:"filesSeen"`
+	FilesParsed  int64 `bson:"filesParsed" json:"filesParsed"`
+	FilesFailed  int64 `bson:"filesFailed" json:"filesFailed"`
+	FilesIndexed int64 `bson:"filesIndexed" json:"filesIndexed"`
+}
+
+// Job records the progress of an ingestion task from the queue.
+type Job struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type       string             `bson:"type" json:"type"`
+	Source     string             `bson:"source" json:"source"`
+	State      string             `bson:"state" json:"state"`
+	Counters   JobCounters        `bson:"counters" json:"counters"`
+	Error      string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time          `bson:"updatedAt" json:"updatedAt"`
+	FinishedAt *time.Time         `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+}
+
+// APIKey is a key granting a role on the API. Only the SHA-256 hash of the
+// key is stored; Prefix is kept to recognize a key in listings.
+type APIKey struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Role      string             `bson:"role" json:"role"`
+	Prefix    string             `bson:"prefix" json:"prefix"`
+	Hash      string             `bson:"hash" json:"-"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	RevokedAt *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	// DailyQuotas overrides the configured daily request quota of a route
+	// group for this key.
+	DailyQuotas map[string]int64 `bson:"dailyQuotas,omitempty" json:"dailyQuotas,omitempty"`
+}
+
+// QuotaUsage counts the requests of a caller to a route group on one UTC day.
+type QuotaUsage struct {
+	ID        string    `bson:"_id" json:"id"`
+	Caller    string    `bson:"caller" json:"caller"`
+	Group     string    `bson:"group" json:"group"`
+	Day       string    `bson:"day" json:"day"`
+	Count     int64     `bson:"count" json:"count"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
+
+// IdempotencyKey records the job created by a request sent with an
+// Idempotency-Key header, so that repeating the request returns the same job.
+type IdempotencyKey struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	// APIKeyID is the hex ID of the API key that sent the key, or empty when
+	// authentication is disabled. Keys of different callers never collide.
+	APIKeyID    string    `bson:"apiKeyID" json:"apiKeyId"`
+	Key         string    `bson:"key" json:"key"`
+	RequestHash string    `bson:"requestHash" json:"requestHash"`
+	JobID       string    `bson:"jobId" json:"jobId"`
+	CreatedAt   time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// QueryLog records a single search request for analytics. Caller is the ID of
+// the API key, or the client IP when authentication is disabled.
+type QueryLog struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Endpoint  string             `bson:"endpoint" json:"endpoint"`
+	Query     string             `bson:"query" json:"query"`
+	Filters   map[string]string  `bson:"filters,omitempty" json:"filters,omitempty"`
+	Hits      int                `bson:"hits" json:"hits"`
+	LatencyMS float64            `bson:"latencyMs" json:"latencyMs"`
+	Caller    string             `bson:"caller" json:"caller"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// QueryCount is the number of times a normalized query was searched.
+type QueryCount struct {
+	Query    string    `bson:"_id" json:"query"`
+	Count    int       `bson:"count" json:"count"`
+	AvgHits  float64   `bson:"avgHits" json:"avgHits"`
+	LastSeen time.Time `bson:"lastSeen" json:"lastSeen"`
+}
+
+// LatencyReport summarizes search latencies, in milliseconds, over a window.
+type LatencyReport struct {
+	Count       int64              `json:"count"`
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+// Alert records a newly indexed patent that matched a saved search. It carries
+// the API key ID of the saved search, so that only its owner sees it.
+type Alert struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SavedSearchID   primitive.ObjectID `bson:"savedSearchID" json:"savedSearchId"`
+	APIKeyID        string             `bson:"apiKeyID" json:"apiKeyId"`
+	SavedSearchName string             `bson:"savedSearchName" json:"savedSearchName"`
+	PatentStorageID string             `bson:"patentStorageID" json:"patentStorageId"`
+	PatentNumber    string             `bson:"patentNumber" json:"patentNumber"`
+	PatentTitle     string             `bson:"patentTitle" json:"patentTitle"`
+	Delivered       bool               `bson:"delivered" json:"delivered"`
+	DeliveryError   string             `bson:"deliveryError,omitempty" json:"deliveryError,omitempty"`
+	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
 type Database struct {
 	Client     *mongo.Client
 	Config     *config.Config
@@ -207,7 +373,7 @@ type UsPatentGrant struct {
 			} `xml:"main-cpc" json:"main-cpc,omitempty"`
 			FurtherCpc struct {
 				Text              string `xml:",chardata" json:"text,omitempty"`
-				ClassificationCpc struct {
+				ClassificationCpc []struct {
 					Text                string `xml:",chardata" json:"text,omitempty"`
 					CpcVersionIndicator struct {
 						Text string `xml:",chardata" json:"text,omitempty"`
@@ -0,0 +1,138 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureQueryLog creates the capped query log collection and its index if
+// they do not exist yet. Once the collection reaches QueryLogMaxBytes, the
+// oldest entries are discarded.
+func (db *Database) EnsureQueryLog() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	database := db.Client.Database(db.Config.MongoDBConfig.Database)
+	name := db.Config.MongoDBConfig.QueryLogCollectionName
+
+	names, err := database.ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		return fmt.Errorf("error listing MongoDB collections: %v", err)
+	}
+	if len(names) == 0 {
+		opts := options.CreateCollection().SetCapped(true).SetSizeInBytes(db.Config.MongoDBConfig.QueryLogMaxBytes)
+		if err := database.CreateCollection(ctx, name, opts); err != nil {
+			return fmt.Errorf("error creating query log collection: %v", err)
+		}
+	}
+
+	_, err = database.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "createdAt", Value: 1}}})
+	if err != nil {
+		return fmt.Errorf("error creating query log index: %v", err)
+	}
+	return nil
+}
+
+// StoreQueryLogs inserts a batch of query log entries.
+func (db *Database) StoreQueryLogs(entries []QueryLog) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.QueryLogCollectionName)
+
+	documents := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		documents = append(documents, entry)
+	}
+	if _, err := collection.InsertMany(ctx, documents, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("error storing query logs to MongoDB: %v", err)
+	}
+	return nil
+}
+
+// TopQueries returns the queries searched most often since the given time.
+func (db *Database) TopQueries(since time.Time, limit int64) ([]QueryCount, error) {
+	return db.countQueries(bson.M{"createdAt": bson.M{"$gte": since}}, limit)
+}
+
+// ZeroResultQueries returns the queries that most often matched nothing
+// since the given time.
+func (db *Database) ZeroResultQueries(since time.Time, limit int64) ([]QueryCount, error) {
+	return db.countQueries(bson.M{"createdAt": bson.M{"$gte": since}, "hits": 0}, limit)
+}
+
+func (db *Database) countQueries(match bson.M, limit int64) ([]QueryCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.QueryLogCollectionName)
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$query",
+			"count":    bson.M{"$sum": 1},
+			"avgHits":  bson.M{"$avg": "$hits"},
+			"lastSeen": bson.M{"$max": "$createdAt"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating query logs: %v", err)
+	}
+
+	counts := []QueryCount{}
+	if err := cursor.All(ctx, &counts); err != nil {
+		return nil, fmt.Errorf("error decoding query counts: %v", err)
+	}
+	return counts, nil
+}
+
+// SearchLatency returns the nearest-rank latency percentiles of the searches
+// logged since the given time. Percentiles are between 0 and 100.
+func (db *Database) SearchLatency(since time.Time, percentiles []float64) (*LatencyReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.QueryLogCollectionName)
+
+	match := bson.M{"createdAt": bson.M{"$gte": since}}
+	count, err := collection.CountDocuments(ctx, match)
+	if err != nil {
+		return nil, fmt.Errorf("error counting query logs: %v", err)
+	}
+
+	report := &LatencyReport{Count: count, Percentiles: map[string]float64{}}
+	if count == 0 {
+		return report, nil
+	}
+	for _, p := range percentiles {
+		rank := int64(math.Ceil(p / 100 * float64(count)))
+		if rank < 1 {
+			rank = 1
+		}
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$sort", Value: bson.M{"latencyMs": 1}}},
+			{{Key: "$skip", Value: rank - 1}},
+			{{Key: "$limit", Value: 1}},
+			{{Key: "$project", Value: bson.M{"latencyMs": 1}}},
+		}
+		cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+		if err != nil {
+			return nil, fmt.Errorf("error aggregating query logs: %v", err)
+		}
+		var entries []QueryLog
+		if err := cursor.All(ctx, &entries); err != nil {
+			return nil, fmt.Errorf("error decoding query logs: %v", err)
+		}
+		if len(entries) > 0 {
+			report.Percentiles[fmt.Sprintf("p%g", p)] = entries[0].LatencyMS
+		}
+	}
+	return report, nil
+}
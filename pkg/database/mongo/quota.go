@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureQuotas creates the index that removes quota counters once their day
+// is over.
+func (db *Database) EnsureQuotas() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.QuotaCollectionName)
+
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return fmt.Errorf("error creating quota index: %v", err)
+	}
+	return nil
+}
+
+// IncrementQuotaUsage counts a request of caller to a route group on the UTC
+// day of now and returns the updated counter.
+func (db *Database) IncrementQuotaUsage(caller, group string, now time.Time) (*QuotaUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.QuotaCollectionName)
+
+	day := now.UTC().Truncate(24 * time.Hour)
+	dayName := day.Format("2006-01-02")
+	update := bson.M{
+		"$inc": bson.M{"count": 1},
+		"$setOnInsert": bson.M{
+			"caller": caller,
+			"group":  group,
+			"day":    dayName,
+			// Kept for a day after it ends, for inspection.
+			"expiresAt": day.Add(48 * time.Hour),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var usage QuotaUsage
+	id := caller + ":" + group + ":" + dayName
+	if err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&usage); err != nil {
+		return nil, fmt.Errorf("error updating quota usage in MongoDB: %v", err)
+	}
+	return &usage, nil
+}
@@ -2,7 +2,9 @@ package mongo
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -10,6 +12,17 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ErrNotFound is returned when a requested document does not exist.
+var ErrNotFound = errors.New("document not found")
+
+// ParseStorageID converts a storage ID to the ObjectID of the original XML
+// document. Patents stored by earlier versions have storage IDs of the form
+// ObjectID("<hex>"), which are accepted too.
+func ParseStorageID(storageID string) (primitive.ObjectID, error) {
+	hex := strings.TrimSuffix(strings.TrimPrefix(storageID, `ObjectID("`), `")`)
+	return primitive.ObjectIDFromHex(hex)
+}
+
 func (db *Database) StoreXML(data map[string]interface{}) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -21,9 +34,27 @@ func (db *Database) StoreXML(data map[string]interface{}) (string, error) {
 	}
 
 	// Return the inserted ID
+	if objID, ok := result.InsertedID.(primitive.ObjectID); ok {
+		return objID.Hex(), nil
+	}
 	return fmt.Sprintf("%v", result.InsertedID), nil
 }
 
+// EnsurePatentIndexes creates the index on the storage ID of patents, which
+// RetrievePatent looks them up by. It is not unique, so that it can be built
+// over patents stored before it existed.
+func (db *Database) EnsurePatentIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.IndexCollectionName)
+
+	index := mongo.IndexModel{Keys: bson.D{{Key: "patentStorageID", Value: 1}}}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		return fmt.Errorf("error creating patent storage ID index: %v", err)
+	}
+	return nil
+}
+
 func (db *Database) StorePatent(patent *Patent) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -38,6 +69,8 @@ func (db *Database) StorePatent(patent *Patent) (string, error) {
 	return fmt.Sprintf("%v", result.InsertedID), nil
 }
 
+// RetrievePatent returns the normalized patent built from the XML document
+// with the given storage ID. It returns ErrNotFound if there is none.
 func (db *Database) RetrievePatent(patentStorageID string) (*Patent, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -45,19 +78,19 @@ func (db *Database) RetrievePatent(patentStorageID string) (*Patent, error) {
 	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.IndexCollectionName)
 
 	// Convert string ID to ObjectID
-	objID, err := primitive.ObjectIDFromHex(patentStorageID)
+	objID, err := ParseStorageID(patentStorageID)
 	if err != nil {
 		return nil, fmt.Errorf("error converting string ID to ObjectID: %v", err)
 	}
 
-	// Create a filter to match the document
-	filter := bson.M{"_id": objID}
+	// Match both the current and the legacy form of the storage ID
+	filter := bson.M{"patentStorageID": bson.M{"$in": bson.A{objID.Hex(), fmt.Sprintf("%v", objID)}}}
 
 	var patent Patent
 	err = collection.FindOne(ctx, filter).Decode(&patent)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("no patent found with ID: %s", patentStorageID)
+			return nil, fmt.Errorf("no patent found with ID %s: %w", patentStorageID, ErrNotFound)
 		}
 		return nil, fmt.Errorf("error retrieving patent from MongoDB: %v", err)
 	}
@@ -65,6 +98,8 @@ func (db *Database) RetrievePatent(patentStorageID string) (*Patent, error) {
 	return &patent, nil
 }
 
+// RetrieveXML returns the original XML document, as stored by StoreXML. It
+// returns ErrNotFound if there is none.
 func (db *Database) RetrieveXML(xmlStorageID string) (map[string]interface{}, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -72,7 +107,7 @@ func (db *Database) RetrieveXML(xmlStorageID string) (map[string]interface{}, er
 	collection := db.Client.Database(db.Config.MongoDBConfig.Database).Collection(db.Config.MongoDBConfig.StorageCollectionName)
 
 	// Convert string ID to ObjectID
-	objID, err := primitive.ObjectIDFromHex(xmlStorageID)
+	objID, err := ParseStorageID(xmlStorageID)
 	if err != nil {
 		return nil, fmt.Errorf("error converting string ID to ObjectID: %v", err)
 	}
@@ -84,7 +119,7 @@ func (db *Database) RetrieveXML(xmlStorageID string) (map[string]interface{}, er
 	err = collection.FindOne(ctx, filter).Decode(&xmlData)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("no XML data found with ID: %s", xmlStorageID)
+			return nil, fmt.Errorf("no XML data found with ID %s: %w", xmlStorageID, ErrNotFound)
 		}
 		return nil, fmt.Errorf("error retrieving XML data from MongoDB: %v", err)
 	}
@@ -2,21 +2,36 @@ package downloader
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/avyukth/search-app/pkg/config"
 )
 
+var (
+	// ErrLinkNotLive is returned when a link does not answer with 200 OK.
+	ErrLinkNotLive = errors.New("Link is not live")
+	// ErrUnsafeArchive is returned when an archive entry would be extracted
+	// outside the extraction directory.
+	ErrUnsafeArchive = errors.New("archive entry is outside the extraction directory")
+	// ErrArchiveTooLarge is returned when the files extracted from an archive
+	// add up to more than the extraction limit.
+	ErrArchiveTooLarge = errors.New("archive expands beyond the extraction limit")
+)
+
 type Downloader interface {
 	Download(ctx context.Context, link string) (string, error)
 	ExtractTarGz(filePath string) (string, error)
+	ExtractZip(filePath string) (string, error)
 }
 
 type HTTPDownloader struct {
@@ -46,7 +61,7 @@ func (d *HTTPDownloader) Download(ctx context.Context, link string) (string, err
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("non-200 status code received for link %s: %s", link, resp.Status)
+		return "", fmt.Errorf("%w: non-200 status code received for link %s: %s", ErrLinkNotLive, link, resp.Status)
 	}
 
 	dir, err := os.Getwd()
@@ -74,6 +89,24 @@ func (d *HTTPDownloader) Download(ctx context.Context, link string) (string, err
 	return destPath, nil
 }
 
+// CheckLink returns ErrLinkNotLive unless a HEAD request to link answers with
+// 200 OK, so that ingestions of dead links are refused before a job starts.
+func CheckLink(ctx context.Context, client *http.Client, link string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLinkNotLive, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLinkNotLive, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", ErrLinkNotLive, resp.Status)
+	}
+	return nil
+}
+
 func (d *HTTPDownloader) ExtractTarGz(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -89,6 +122,7 @@ func (d *HTTPDownloader) ExtractTarGz(filePath string) (string, error) {
 
 	tarReader := tar.NewReader(gzReader)
 	destDir := filepath.Dir(filePath)
+	limit := d.extractLimit()
 
 	for {
 		header, err := tarReader.Next()
@@ -99,23 +133,120 @@ func (d *HTTPDownloader) ExtractTarGz(filePath string) (string, error) {
 			return "", fmt.Errorf("reading tar header: %w", err)
 		}
 
-		target := filepath.Join(destDir, header.Name)
+		target, err := extractPath(destDir, header.Name)
+		if err != nil {
+			return "", err
+		}
 		switch header.Typeflag {
 		case tar.TypeDir:
 			if err := os.MkdirAll(target, 0755); err != nil {
 				return "", fmt.Errorf("creating directory %s: %w", target, err)
 			}
 		case tar.TypeReg:
-			outFile, err := os.Create(target)
-			if err != nil {
-				return "", fmt.Errorf("creating file %s: %w", target, err)
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", fmt.Errorf("creating directory %s: %w", filepath.Dir(target), err)
 			}
-			if _, err := io.Copy(outFile, tarReader); err != nil {
-				outFile.Close()
-				return "", fmt.Errorf("writing to file %s: %w", target, err)
+			if err := limit.extract(tarReader, target); err != nil {
+				return "", err
 			}
-			outFile.Close()
 		}
 	}
 	return destDir, nil
 }
+
+// ExtractZip extracts the zip archive at filePath next to it and returns the
+// directory it was extracted to.
+func (d *HTTPDownloader) ExtractZip(filePath string) (string, error) {
+	zipReader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("opening zip archive %s: %w", filePath, err)
+	}
+	defer zipReader.Close()
+
+	destDir := filepath.Dir(filePath)
+	limit := d.extractLimit()
+	for _, entry := range zipReader.File {
+		target, err := extractPath(destDir, entry.Name)
+		if err != nil {
+			return "", err
+		}
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", fmt.Errorf("creating directory %s: %w", target, err)
+			}
+			continue
+		}
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		if err := extractZipFile(entry, target, limit); err != nil {
+			return "", err
+		}
+	}
+	return destDir, nil
+}
+
+func extractZipFile(entry *zip.File, target string, limit *extractLimit) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("creating directory %s: %w", filepath.Dir(target), err)
+	}
+	in, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("opening %s in zip archive: %w", entry.Name, err)
+	}
+	defer in.Close()
+	return limit.extract(in, target)
+}
+
+// extractLimit caps the bytes written while extracting an archive, so that a
+// small archive cannot expand to fill the disk. A zero max disables it.
+type extractLimit struct {
+	max       int64
+	remaining int64
+}
+
+func (d *HTTPDownloader) extractLimit() *extractLimit {
+	return &extractLimit{max: d.serverConfig.ExtractMaxBytes, remaining: d.serverConfig.ExtractMaxBytes}
+}
+
+// extract writes an archive entry to target. An entry going over the limit is
+// removed and ErrArchiveTooLarge returned.
+func (l *extractLimit) extract(entry io.Reader, target string) error {
+	outFile, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("creating file %s: %w", target, err)
+	}
+	defer outFile.Close()
+
+	if l.max <= 0 {
+		if _, err := io.Copy(outFile, entry); err != nil {
+			return fmt.Errorf("writing to file %s: %w", target, err)
+		}
+		return nil
+	}
+
+	// Reading one byte past the limit tells an entry ending right at the
+	// limit from one going over it.
+	n, err := io.Copy(outFile, io.LimitReader(entry, l.remaining+1))
+	l.remaining -= n
+	if err != nil {
+		return fmt.Errorf("writing to file %s: %w", target, err)
+	}
+	if l.remaining < 0 {
+		outFile.Close()
+		os.Remove(target)
+		return fmt.Errorf("%w of %d bytes at %s", ErrArchiveTooLarge, l.max, filepath.Base(target))
+	}
+	return nil
+}
+
+// extractPath returns where an archive entry is extracted to, rejecting
+// entries that would be written outside destDir.
+func extractPath(destDir, name string) (string, error) {
+	destDir = filepath.Clean(destDir)
+	target := filepath.Join(destDir, name)
+	if target != destDir && !strings.HasPrefix(target, destDir+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchive, name)
+	}
+	return target, nil
+}
@@ -0,0 +1,152 @@
+package downloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/avyukth/search-app/pkg/config"
+)
+
+type archiveEntry struct {
+	name string
+	size int
+}
+
+func writeZip(t *testing.T, path string, entries []archiveEntry) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	w := zip.NewWriter(file)
+	for _, entry := range entries {
+		f, err := w.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(strings.Repeat("a", entry.size))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string, entries []archiveEntry) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	w := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(entry.size), Typeflag: tar.TypeReg}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(strings.Repeat("a", entry.size))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int64
+		entries  []archiveEntry
+		wantErr  error
+		// wantFiles are the files left in the extraction directory.
+		wantFiles []string
+	}{
+		{
+			name:      "within limit",
+			maxBytes:  100,
+			entries:   []archiveEntry{{"a.xml", 50}, {"dir/b.xml", 50}},
+			wantFiles: []string{"a.xml", "dir/b.xml"},
+		},
+		{
+			name:      "entry over limit",
+			maxBytes:  100,
+			entries:   []archiveEntry{{"a.xml", 101}},
+			wantErr:   ErrArchiveTooLarge,
+			wantFiles: []string{},
+		},
+		{
+			name:      "total over limit",
+			maxBytes:  100,
+			entries:   []archiveEntry{{"a.xml", 60}, {"b.xml", 60}},
+			wantErr:   ErrArchiveTooLarge,
+			wantFiles: []string{"a.xml"},
+		},
+		{
+			name:      "no limit",
+			maxBytes:  0,
+			entries:   []archiveEntry{{"a.xml", 1000}},
+			wantFiles: []string{"a.xml"},
+		},
+		{
+			name:      "path traversal",
+			maxBytes:  100,
+			entries:   []archiveEntry{{"../a.xml", 10}},
+			wantErr:   ErrUnsafeArchive,
+			wantFiles: []string{},
+		},
+	}
+
+	formats := []struct {
+		name    string
+		write   func(*testing.T, string, []archiveEntry)
+		extract func(*HTTPDownloader, string) (string, error)
+	}{
+		{"zip", writeZip, (*HTTPDownloader).ExtractZip},
+		{"tar.gz", writeTarGz, (*HTTPDownloader).ExtractTarGz},
+	}
+
+	for _, format := range formats {
+		for _, tt := range tests {
+			t.Run(format.name+"/"+tt.name, func(t *testing.T) {
+				dir := filepath.Join(t.TempDir(), "upload")
+				if err := os.Mkdir(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				archive := filepath.Join(dir, "archive."+format.name)
+				format.write(t, archive, tt.entries)
+
+				d := &HTTPDownloader{serverConfig: &config.ServerConfig{ExtractMaxBytes: tt.maxBytes}}
+				_, err := format.extract(d, archive)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("extract error = %v, want %v", err, tt.wantErr)
+				}
+
+				var files []string
+				filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+					if err == nil && !info.IsDir() && path != archive {
+						rel, _ := filepath.Rel(dir, path)
+						files = append(files, filepath.ToSlash(rel))
+					}
+					return nil
+				})
+				if strings.Join(files, ",") != strings.Join(tt.wantFiles, ",") {
+					t.Errorf("extracted %v, want %v", files, tt.wantFiles)
+				}
+			})
+		}
+	}
+}
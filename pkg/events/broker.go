@@ -0,0 +1,138 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+)
+
+// Event types.
+const (
+	// StateEvent is published when a job moves to a new state.
+	StateEvent = "state"
+	// ProgressEvent is published when the file counters of a job change.
+	ProgressEvent = "progress"
+)
+
+// subscriberBuffer is the number of events buffered for each subscriber. When
+// a subscriber falls behind, its oldest events are discarded.
+const subscriberBuffer = 64
+
+// Event reports the state and cumulative counters of a job.
+type Event struct {
+	Type     string            `json:"type"`
+	JobID    string            `json:"jobId"`
+	State    string            `json:"state"`
+	Counters mongo.JobCounters `json:"counters"`
+	Error    string            `json:"error,omitempty"`
+	Time     time.Time         `json:"time"`
+}
+
+// Final reports whether the event ends its job.
+func (e Event) Final() bool {
+	return e.State == mongo.JobDone || e.State == mongo.JobFailed
+}
+
+// Broker fans out job events to subscribers in the same process. Publishing
+// never blocks the worker.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the events of a job. The channel is
+// closed after the final event of the job, or when cancel is called.
+func (b *Broker) Subscribe(jobID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[jobID] == nil {
+		b.subscribers[jobID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[jobID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[jobID][ch]; ok {
+			b.remove(jobID, ch)
+		}
+	}
+	return ch, cancel
+}
+
+// Watch subscribes to the events of a job and returns its current state as
+// the first event. The subscription is cancelled already when the job has
+// finished.
+func (b *Broker) Watch(db *mongo.Database, jobID string) (Event, <-chan Event, func(), error) {
+	stream, cancel := b.Subscribe(jobID)
+	job, err := db.RetrieveJob(jobID)
+	if err != nil {
+		cancel()
+		return Event{}, nil, nil, err
+	}
+
+	first := Event{
+		Type:     StateEvent,
+		JobID:    jobID,
+		State:    job.State,
+		Counters: job.Counters,
+		Error:    job.Error,
+		Time:     job.UpdatedAt,
+	}
+	if first.Final() {
+		cancel()
+	}
+	return first, stream, cancel, nil
+}
+
+// Publish sends an event to the subscribers of its job. The final event of a
+// job closes every subscription to it.
+func (b *Broker) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[event.JobID] {
+		send(ch, event)
+		if event.Final() {
+			b.remove(event.JobID, ch)
+		}
+	}
+}
+
+// remove closes and forgets a subscription. b.mu must be held.
+func (b *Broker) remove(jobID string, ch chan Event) {
+	delete(b.subscribers[jobID], ch)
+	if len(b.subscribers[jobID]) == 0 {
+		delete(b.subscribers, jobID)
+	}
+	close(ch)
+}
+
+// send delivers event without blocking, discarding the oldest buffered
+// events of a slow subscriber to make room.
+func send(ch chan Event, event Event) {
+	for {
+		select {
+		case ch <- event:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
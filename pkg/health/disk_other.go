@@ -0,0 +1,10 @@
+//go:build !unix
+
+package health
+
+import "errors"
+
+// diskSpace is only implemented on Unix systems.
+func diskSpace(dir string) (free, total uint64, err error) {
+	return 0, 0, errors.New("disk space check not supported on this system")
+}
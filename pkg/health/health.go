@@ -0,0 +1,201 @@
+// Package health checks the dependencies of the server for the liveness and
+// readiness endpoints.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+	"github.com/avyukth/search-app/pkg/queue"
+)
+
+// Statuses of checks and reports. A report has the worst status of its
+// checks.
+const (
+	StatusPass = "pass"
+	// StatusWarn marks a dependency that works but needs attention, such as
+	// a full or paused queue.
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Result is the outcome of checking one dependency. Details holds what was
+// measured, such as the number of indexed documents.
+type Result struct {
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report is the outcome of a set of checks, by dependency.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// OK reports whether no check failed.
+func (r *Report) OK() bool {
+	return r.Status != StatusFail
+}
+
+type check func(ctx context.Context) Result
+
+// Checker checks MongoDB, the search index, the task queue and the disk space
+// under the storage directory.
+type Checker struct {
+	db      *mongo.Database
+	index   indexer.SearchBackend
+	q       *queue.TaskQueue
+	storage string
+	// minFreeBytes is the free disk space below which the disk check fails.
+	minFreeBytes uint64
+	timeout      time.Duration
+}
+
+func NewChecker(db *mongo.Database, index indexer.SearchBackend, q *queue.TaskQueue, cfg *config.Config) *Checker {
+	return &Checker{
+		db:           db,
+		index:        index,
+		q:            q,
+		storage:      cfg.ServerConfig.Storage,
+		minFreeBytes: uint64(cfg.HealthConfig.MinFreeBytes),
+		timeout:      cfg.HealthConfig.CheckTimeout,
+	}
+}
+
+// Live checks what only a restart repairs: the index being open and the
+// queue workers running.
+func (c *Checker) Live(ctx context.Context) *Report {
+	return c.run(ctx, map[string]check{
+		"index": c.checkIndex,
+		"queue": c.checkQueue,
+	})
+}
+
+// Ready checks every dependency needed to serve requests: MongoDB, the index,
+// the queue and the disk space under the storage directory.
+func (c *Checker) Ready(ctx context.Context) *Report {
+	return c.run(ctx, map[string]check{
+		"mongo": c.checkMongo,
+		"index": c.checkIndex,
+		"queue": c.checkQueue,
+		"disk":  c.checkDisk,
+	})
+}
+
+// run runs the checks concurrently, each bounded by the check timeout.
+func (c *Checker) run(ctx context.Context, checks map[string]check) *Report {
+	report := &Report{Status: StatusPass, Checks: make(map[string]Result, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, run := range checks {
+		wg.Add(1)
+		go func(name string, run check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+			result := run(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status == StatusFail || result.Status == StatusWarn && report.Status == StatusPass {
+				report.Status = result.Status
+			}
+		}(name, run)
+	}
+	wg.Wait()
+	return report
+}
+
+func (c *Checker) checkMongo(ctx context.Context) Result {
+	start := time.Now()
+	if err := c.db.Client.Ping(ctx, nil); err != nil {
+		return Result{Status: StatusFail, Error: err.Error()}
+	}
+	return Result{Status: StatusPass, Details: map[string]interface{}{
+		"latencyMs": float64(time.Since(start).Microseconds()) / 1000,
+	}}
+}
+
+func (c *Checker) checkIndex(ctx context.Context) Result {
+	count, err := c.index.DocCount()
+	if err != nil {
+		return Result{Status: StatusFail, Error: err.Error()}
+	}
+	return Result{Status: StatusPass, Details: map[string]interface{}{"documents": count}}
+}
+
+func (c *Checker) checkQueue(ctx context.Context) Result {
+	stats := c.q.Stats()
+	result := Result{Status: StatusPass, Details: map[string]interface{}{
+		"workers":       stats.Workers,
+		"targetWorkers": stats.TargetWorkers,
+		"depth":         stats.Depth,
+		"running":       stats.Running,
+		"capacity":      stats.Capacity,
+	}}
+	switch {
+	case stats.Stopped:
+		result.Status, result.Error = StatusFail, "queue is stopped"
+	case stats.Workers < stats.TargetWorkers:
+		result.Status = StatusFail
+		result.Error = fmt.Sprintf("%d of %d workers running", stats.Workers, stats.TargetWorkers)
+	case stats.Paused:
+		result.Status, result.Error = StatusWarn, "queue is paused"
+	case stats.Draining:
+		result.Status, result.Error = StatusWarn, "queue is draining, ingestions are rejected"
+	case stats.Depth >= stats.Capacity:
+		result.Status, result.Error = StatusWarn, "queue is full, ingestions are rejected"
+	}
+	return result
+}
+
+func (c *Checker) checkDisk(ctx context.Context) Result {
+	dir, err := existingDir(c.storage)
+	if err != nil {
+		return Result{Status: StatusFail, Error: err.Error()}
+	}
+	free, total, err := diskSpace(dir)
+	if err != nil {
+		return Result{Status: StatusFail, Error: err.Error()}
+	}
+	result := Result{Status: StatusPass, Details: map[string]interface{}{
+		"path":       dir,
+		"freeBytes":  free,
+		"totalBytes": total,
+	}}
+	if free < c.minFreeBytes {
+		result.Status = StatusFail
+		result.Error = fmt.Sprintf("%d bytes free, at least %d required", free, c.minFreeBytes)
+	}
+	return result
+}
+
+// existingDir returns dir, or its closest existing parent when it has not
+// been created yet, which is on the same file system dir will be.
+func existingDir(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		_, err := os.Stat(dir)
+		if err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if !errors.Is(err, os.ErrNotExist) || parent == dir {
+			return "", err
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,241 @@
+package indexer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/char/asciifolding"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/token/stop"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/analysis/tokenmap"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/registry"
+)
+
+const (
+	// PatentAnalyzer is the default analyzer of indexes created by this package.
+	PatentAnalyzer = "patent"
+	// PlainAnalyzer folds and lower cases words without removing stop words or
+	// stemming. It is used for names and typed prefixes.
+	PlainAnalyzer = "patent_plain"
+
+	// SynonymFilterType is the bleve token filter type backed by Synonyms.
+	SynonymFilterType = "patent_synonym"
+
+	patentStopWords   = "patent_stop_words"
+	patentStopFilter  = "patent_stop"
+	patentSynonymName = "patent_synonyms"
+)
+
+// PatentStopWords are boilerplate words that appear in most design patent
+// titles and carry no meaning for search.
+var PatentStopWords = []string{
+	"ornamental", "design", "designs", "for", "a", "an", "the", "of", "or", "and",
+	"article", "articles", "thereof", "therefor", "same", "like", "similar",
+}
+
+// Synonyms is the synonym set used by every patent analyzer in the process.
+var Synonyms = &SynonymSet{}
+
+func init() {
+	registry.RegisterTokenFilter(SynonymFilterType, func(config map[string]interface{}, cache *registry.Cache) (analysis.TokenFilter, error) {
+		return &synonymFilter{set: Synonyms}, nil
+	})
+}
+
+// SynonymSet maps each term to the other terms of its synonym group. It can be
+// reloaded while indexes using it are open.
+type SynonymSet struct {
+	mu     sync.RWMutex
+	path   string
+	groups map[string][]string
+}
+
+// Load reads a synonym file and replaces the current set. Each non-empty line
+// that does not start with # is a comma-separated group of equivalent terms,
+// for example "container, receptacle, vessel".
+func (s *SynonymSet) Load(path string) (int, error) {
+	groups := map[string][]string{}
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, fmt.Errorf("opening synonym file %s: %w", path, err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			var terms []string
+			for _, term := range strings.Split(line, ",") {
+				if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+					terms = append(terms, term)
+				}
+			}
+			for _, term := range terms {
+				for _, other := range terms {
+					if other != term {
+						groups[term] = append(groups[term], other)
+					}
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return 0, fmt.Errorf("reading synonym file %s: %w", path, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.path = path
+	s.groups = groups
+	s.mu.Unlock()
+	return len(groups), nil
+}
+
+// Reload reads the synonym file last passed to Load again.
+func (s *SynonymSet) Reload() (int, error) {
+	s.mu.RLock()
+	path := s.path
+	s.mu.RUnlock()
+	return s.Load(path)
+}
+
+func (s *SynonymSet) lookup(term string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.groups[term]
+}
+
+// synonymFilter emits the synonyms of each token at the token's position, so
+// that a document or query containing any term of a group matches the others.
+type synonymFilter struct {
+	set *SynonymSet
+}
+
+func (f *synonymFilter) Filter(input analysis.TokenStream) analysis.TokenStream {
+	output := make(analysis.TokenStream, 0, len(input))
+	for _, token := range input {
+		output = append(output, token)
+		for _, synonym := range f.set.lookup(string(token.Term)) {
+			output = append(output, &analysis.Token{
+				Term:     []byte(synonym),
+				Start:    token.Start,
+				End:      token.End,
+				Position: token.Position,
+				Type:     token.Type,
+			})
+		}
+	}
+	return output
+}
+
+// NewIndexMapping builds the mapping for new indexes: text is ASCII folded,
+// lower cased, stripped of English and patent boilerplate stop words, expanded
+// with synonyms and, when enabled, stemmed.
+func NewIndexMapping(cfg *config.AnalyzerConfig) (*mapping.IndexMappingImpl, error) {
+	indexMapping := bleve.NewIndexMapping()
+
+	stopWords := make([]interface{}, 0, len(PatentStopWords))
+	for _, word := range PatentStopWords {
+		stopWords = append(stopWords, word)
+	}
+	if err := indexMapping.AddCustomTokenMap(patentStopWords, map[string]interface{}{
+		"type":   tokenmap.Name,
+		"tokens": stopWords,
+	}); err != nil {
+		return nil, err
+	}
+	if err := indexMapping.AddCustomTokenFilter(patentStopFilter, map[string]interface{}{
+		"type":           stop.Name,
+		"stop_token_map": patentStopWords,
+	}); err != nil {
+		return nil, err
+	}
+	if err := indexMapping.AddCustomTokenFilter(patentSynonymName, map[string]interface{}{
+		"type": SynonymFilterType,
+	}); err != nil {
+		return nil, err
+	}
+
+	tokenFilters := []interface{}{lowercase.Name, en.StopName, patentStopFilter, patentSynonymName}
+	if cfg.Stemming {
+		tokenFilters = append(tokenFilters, en.SnowballStemmerName)
+	}
+	if err := indexMapping.AddCustomAnalyzer(PatentAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"char_filters":  []interface{}{asciifolding.Name},
+		"tokenizer":     unicode.Name,
+		"token_filters": tokenFilters,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := indexMapping.AddCustomAnalyzer(PlainAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"char_filters":  []interface{}{asciifolding.Name},
+		"tokenizer":     unicode.Name,
+		"token_filters": []interface{}{lowercase.Name},
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := addSuggestMappings(indexMapping); err != nil {
+		return nil, err
+	}
+	if err := addInventorMappings(indexMapping); err != nil {
+		return nil, err
+	}
+	if err := addSpellingMappings(indexMapping); err != nil {
+		return nil, err
+	}
+
+	indexMapping.DefaultAnalyzer = PatentAnalyzer
+	return indexMapping, nil
+}
+
+// AnalyzedToken is a single token produced by an analyzer.
+type AnalyzedToken struct {
+	Term     string `json:"term"`
+	Position int    `json:"position"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// Analyze runs text through the named analyzer of the index mapping. An empty
+// name selects the mapping's default analyzer.
+func (se *SearchEngine) Analyze(analyzerName, text string) ([]AnalyzedToken, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	indexMapping := se.index.Mapping()
+	if analyzerName == "" {
+		analyzerName = indexMapping.AnalyzerNameForPath("")
+	}
+	analyzer := indexMapping.AnalyzerNamed(analyzerName)
+	if analyzer == nil {
+		return nil, fmt.Errorf("unknown analyzer %q: %w", analyzerName, ErrInvalidAnalyzer)
+	}
+
+	tokens := []AnalyzedToken{}
+	for _, token := range analyzer.Analyze([]byte(text)) {
+		tokens = append(tokens, AnalyzedToken{
+			Term:     string(token.Term),
+			Position: token.Position,
+			Start:    token.Start,
+			End:      token.End,
+		})
+	}
+	return tokens, nil
+}
@@ -0,0 +1,91 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+const (
+	// BleveBackend stores the index on disk under the configured storage directory.
+	BleveBackend = "bleve"
+	// MemoryBackend keeps the index in memory; it is lost when the process exits.
+	MemoryBackend = "memory"
+)
+
+var (
+	// ErrNotFound is returned when a patent is not present in the index.
+	ErrNotFound = errors.New("patent not found in index")
+	// ErrInvalidAnalyzer is returned when an analyzer is not defined by the index mapping.
+	ErrInvalidAnalyzer = errors.New("invalid analyzer")
+)
+
+// SearchBackend is the set of index operations used by the API, the worker
+// and the alert evaluator.
+type SearchBackend interface {
+	IndexPatent(patent *mongo.Patent) error
+	IndexPatents(patents []*mongo.Patent) error
+	DeletePatent(patentID string) error
+	SearchAndRetrievePatents(ctx context.Context, searchTerm string) ([]mongo.Patent, error)
+	Search(ctx context.Context, req *SearchRequest) (*SearchResult, error)
+	ExportPatents(ctx context.Context, searchTerm string) PatentCursor
+	FacetPatents(searchTerm, field string, size int) ([]FacetCount, error)
+	LookupPatent(patentID string) (*mongo.Patent, error)
+	MatchesPatent(patentID, searchTerm string, filters map[string]string) (bool, error)
+	Analyze(analyzerName, text string) ([]AnalyzedToken, error)
+	Suggest(text string, fields []string, size int) (map[string][]Suggestion, error)
+	SearchInventors(name string, size int) ([]InventorMatch, error)
+	SuggestSpelling(searchTerm string) (*SpellingResult, error)
+	DocCount() (uint64, error)
+	Snapshot(dir string) error
+	Restore(srcDir string) error
+	Close() error
+}
+
+// FacetCount is the number of matching patents sharing a field term.
+type FacetCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// NewSearchBackend loads the configured synonyms and creates the backend
+// selected by cfg.SearchBackend.
+func NewSearchBackend(cfg *config.Config) (SearchBackend, error) {
+	if _, err := Synonyms.Load(cfg.AnalyzerConfig.SynonymFile); err != nil {
+		return nil, err
+	}
+	indexMapping, err := NewIndexMapping(&cfg.AnalyzerConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var engine *SearchEngine
+	switch cfg.ServerConfig.SearchBackend {
+	case BleveBackend, "":
+		engine, err = NewSearchEngine(cfg.ServerConfig.Storage+cfg.ServerConfig.IndexDirectory, indexMapping)
+	case MemoryBackend:
+		engine, err = NewMemSearchEngine(indexMapping)
+	default:
+		return nil, fmt.Errorf("unsupported search backend: %q", cfg.ServerConfig.SearchBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	engine.SetQueryLimits(NewQueryLimits(&cfg.ServerConfig))
+	return engine, nil
+}
+
+// NewMemSearchEngine creates a SearchEngine backed by a memory-only bleve
+// index, for tests and ephemeral development servers.
+func NewMemSearchEngine(indexMapping mapping.IndexMapping) (*SearchEngine, error) {
+	index, err := bleve.NewMemOnly(indexMapping)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchEngine{index: index, limits: DefaultQueryLimits}, nil
+}
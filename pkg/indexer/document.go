@@ -0,0 +1,63 @@
+package indexer
+
+import "github.com/avyukth/search-app/pkg/database/mongo"
+
+// patentDocument is what gets indexed for a patent. It carries the
+// mongo.Patent fields under the same names, so that queries and filters can
+// target them, plus derived fields that only exist in the index.
+type patentDocument struct {
+	PatentTitle     string
+	PatentNumber    string
+	InventorNames   []string
+	AssigneeName    string
+	ApplicationDate string
+	IssueDate       string
+	DesignClass     string
+	PatentStorageID string
+
+	// Suggestion fields, see suggest.go.
+	TitleSuggest    string
+	TitleTerm       string
+	AssigneeSuggest string
+	AssigneeTerm    string
+	InventorSuggest []string
+	InventorTerm    []string
+
+	// Inventor name matching fields, see inventor.go.
+	InventorLast     []string
+	InventorPhonetic []string
+
+	// Spelling correction vocabulary, see spelling.go.
+	Spelling []string
+}
+
+func newPatentDocument(patent *mongo.Patent) *patentDocument {
+	var lastNames, phonetic []string
+	for _, name := range inventorNames(patent) {
+		lastNames = append(lastNames, name.Last)
+		phonetic = append(phonetic, phoneticCodes(name.Last)...)
+	}
+
+	return &patentDocument{
+		PatentTitle:     patent.PatentTitle,
+		PatentNumber:    patent.PatentNumber,
+		InventorNames:   patent.InventorNames,
+		AssigneeName:    patent.AssigneeName,
+		ApplicationDate: patent.ApplicationDate,
+		IssueDate:       patent.IssueDate,
+		DesignClass:     patent.DesignClass,
+		PatentStorageID: patent.PatentStorageID,
+
+		TitleSuggest:    patent.PatentTitle,
+		TitleTerm:       patent.PatentTitle,
+		AssigneeSuggest: patent.AssigneeName,
+		AssigneeTerm:    patent.AssigneeName,
+		InventorSuggest: patent.InventorNames,
+		InventorTerm:    patent.InventorNames,
+
+		InventorLast:     lastNames,
+		InventorPhonetic: phonetic,
+
+		Spelling: append([]string{patent.PatentTitle, patent.AssigneeName}, patent.InventorNames...),
+	}
+}
@@ -0,0 +1,397 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// Limits applied by SearchRequest.Validate.
+const (
+	DefaultSearchSize = 10
+	MaxSearchSize     = 100
+	MaxSearchWindow   = 10000
+	MaxClauseDepth    = 5
+	MaxClauses        = 64
+	MaxFacets         = 10
+	MaxFacetSize      = 100
+	MinPrefixLength   = 2
+	MaxFuzziness      = 2
+)
+
+// SearchRequest is the JSON query DSL accepted by POST /search.
+type SearchRequest struct {
+	Query  *Clause                 `json:"query"`
+	From   int                     `json:"from"`
+	Size   int                     `json:"size"`
+	Sort   []string                `json:"sort"`
+	Facets map[string]FacetRequest `json:"facets"`
+}
+
+// FacetRequest asks for the most frequent terms of a field among the matches.
+type FacetRequest struct {
+	Field string `json:"field"`
+	Size  int    `json:"size"`
+}
+
+// Clause is a node of the query tree. Exactly one member must be set.
+type Clause struct {
+	Bool   *BoolClause   `json:"bool,omitempty"`
+	Match  *MatchClause  `json:"match,omitempty"`
+	Phrase *PhraseClause `json:"phrase,omitempty"`
+	Prefix *PrefixClause `json:"prefix,omitempty"`
+	Fuzzy  *FuzzyClause  `json:"fuzzy,omitempty"`
+	Range  *RangeClause  `json:"range,omitempty"`
+}
+
+// BoolClause combines clauses. A document must match every Must clause, no
+// MustNot clause and, when present, at least MinimumShouldMatch Should clauses.
+type BoolClause struct {
+	Must               []Clause `json:"must"`
+	Should             []Clause `json:"should"`
+	MustNot            []Clause `json:"must_not"`
+	MinimumShouldMatch int      `json:"minimum_should_match"`
+}
+
+// MatchClause analyzes Text and matches any (operator "or", the default) or
+// all (operator "and") of its terms in Field.
+type MatchClause struct {
+	Field    string `json:"field"`
+	Text     string `json:"text"`
+	Operator string `json:"operator"`
+}
+
+// PhraseClause matches the terms of Text in order in Field.
+type PhraseClause struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// PrefixClause matches terms of Field starting with Prefix.
+type PrefixClause struct {
+	Field  string `json:"field"`
+	Prefix string `json:"prefix"`
+}
+
+// FuzzyClause matches terms of Field within Fuzziness edits of Term.
+type FuzzyClause struct {
+	Field     string `json:"field"`
+	Term      string `json:"term"`
+	Fuzziness int    `json:"fuzziness"`
+}
+
+// RangeClause matches Field values between the given bounds. Dates use the
+// USPTO YYYYMMDD form, so bounds are compared as strings.
+type RangeClause struct {
+	Field string  `json:"field"`
+	GT    *string `json:"gt,omitempty"`
+	GTE   *string `json:"gte,omitempty"`
+	LT    *string `json:"lt,omitempty"`
+	LTE   *string `json:"lte,omitempty"`
+}
+
+// SearchResult is a page of matching patents with the requested facets.
+type SearchResult struct {
+	Total  uint64                  `json:"total"`
+	From   int                     `json:"from"`
+	Size   int                     `json:"size"`
+	Hits   []mongo.Patent          `json:"hits"`
+	Facets map[string][]FacetCount `json:"facets,omitempty"`
+}
+
+// FieldError describes a single problem with a SearchRequest.
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found in a SearchRequest.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		messages = append(messages, fe.Path+": "+fe.Message)
+	}
+	return "invalid search request: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) add(path, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the request against the DSL schema and fills in defaults.
+// It returns a *ValidationError describing every problem found.
+func (r *SearchRequest) Validate() error {
+	verr := &ValidationError{}
+
+	if r.Size == 0 {
+		r.Size = DefaultSearchSize
+	}
+	if r.Size < 0 || r.Size > MaxSearchSize {
+		verr.add("size", "must be between 1 and %d", MaxSearchSize)
+	}
+	if r.From < 0 {
+		verr.add("from", "must not be negative")
+	}
+	if r.From+r.Size > MaxSearchWindow {
+		verr.add("from", "from + size must not exceed %d", MaxSearchWindow)
+	}
+
+	if r.Query != nil {
+		clauses := 0
+		r.Query.validate("query", 1, &clauses, verr)
+		if clauses > MaxClauses {
+			verr.add("query", "has %d clauses, at most %d are allowed", clauses, MaxClauses)
+		}
+	}
+
+	for i, s := range r.Sort {
+		field := strings.TrimPrefix(s, "-")
+		if field != "_score" && !IsSearchableField(field) {
+			verr.add(fmt.Sprintf("sort[%d]", i), "unknown field %q, expected _score or one of %v", field, SearchableFields)
+		}
+	}
+
+	if len(r.Facets) > MaxFacets {
+		verr.add("facets", "at most %d facets are allowed", MaxFacets)
+	}
+	for name, facet := range r.Facets {
+		path := "facets." + name
+		if !IsSearchableField(facet.Field) {
+			verr.add(path+".field", "unknown field %q, expected one of %v", facet.Field, SearchableFields)
+		}
+		if facet.Size == 0 {
+			facet.Size = DefaultSearchSize
+			r.Facets[name] = facet
+		}
+		if facet.Size < 0 || facet.Size > MaxFacetSize {
+			verr.add(path+".size", "must be between 1 and %d", MaxFacetSize)
+		}
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func (c *Clause) validate(path string, depth int, clauses *int, verr *ValidationError) {
+	*clauses++
+	if depth > MaxClauseDepth {
+		verr.add(path, "clauses may be nested at most %d levels deep", MaxClauseDepth)
+		return
+	}
+
+	set := 0
+	for _, present := range []bool{c.Bool != nil, c.Match != nil, c.Phrase != nil, c.Prefix != nil, c.Fuzzy != nil, c.Range != nil} {
+		if present {
+			set++
+		}
+	}
+	if set != 1 {
+		verr.add(path, "exactly one of bool, match, phrase, prefix, fuzzy or range must be set")
+		return
+	}
+
+	switch {
+	case c.Bool != nil:
+		b := c.Bool
+		if len(b.Must)+len(b.Should)+len(b.MustNot) == 0 {
+			verr.add(path+".bool", "at least one of must, should or must_not must be set")
+		}
+		if len(b.Must)+len(b.Should) == 0 && len(b.MustNot) > 0 {
+			verr.add(path+".bool", "must_not requires a must or should clause to exclude from")
+		}
+		if b.MinimumShouldMatch < 0 || b.MinimumShouldMatch > len(b.Should) {
+			verr.add(path+".bool.minimum_should_match", "must be between 0 and the number of should clauses")
+		}
+		for i := range b.Must {
+			b.Must[i].validate(fmt.Sprintf("%s.bool.must[%d]", path, i), depth+1, clauses, verr)
+		}
+		for i := range b.Should {
+			b.Should[i].validate(fmt.Sprintf("%s.bool.should[%d]", path, i), depth+1, clauses, verr)
+		}
+		for i := range b.MustNot {
+			b.MustNot[i].validate(fmt.Sprintf("%s.bool.must_not[%d]", path, i), depth+1, clauses, verr)
+		}
+	case c.Match != nil:
+		validateField(path+".match.field", c.Match.Field, verr)
+		if strings.TrimSpace(c.Match.Text) == "" {
+			verr.add(path+".match.text", "is required")
+		}
+		if op := c.Match.Operator; op != "" && op != "and" && op != "or" {
+			verr.add(path+".match.operator", "must be \"and\" or \"or\", got %q", op)
+		}
+	case c.Phrase != nil:
+		validateField(path+".phrase.field", c.Phrase.Field, verr)
+		if strings.TrimSpace(c.Phrase.Text) == "" {
+			verr.add(path+".phrase.text", "is required")
+		}
+	case c.Prefix != nil:
+		validateField(path+".prefix.field", c.Prefix.Field, verr)
+		if len([]rune(c.Prefix.Prefix)) < MinPrefixLength {
+			verr.add(path+".prefix.prefix", "must be at least %d characters", MinPrefixLength)
+		}
+	case c.Fuzzy != nil:
+		validateField(path+".fuzzy.field", c.Fuzzy.Field, verr)
+		if strings.TrimSpace(c.Fuzzy.Term) == "" {
+			verr.add(path+".fuzzy.term", "is required")
+		}
+		if c.Fuzzy.Fuzziness < 0 || c.Fuzzy.Fuzziness > MaxFuzziness {
+			verr.add(path+".fuzzy.fuzziness", "must be between 0 and %d", MaxFuzziness)
+		}
+	case c.Range != nil:
+		r := c.Range
+		validateField(path+".range.field", r.Field, verr)
+		if r.GT == nil && r.GTE == nil && r.LT == nil && r.LTE == nil {
+			verr.add(path+".range", "at least one of gt, gte, lt or lte must be set")
+		}
+		if r.GT != nil && r.GTE != nil {
+			verr.add(path+".range", "gt and gte are mutually exclusive")
+		}
+		if r.LT != nil && r.LTE != nil {
+			verr.add(path+".range", "lt and lte are mutually exclusive")
+		}
+	}
+}
+
+func validateField(path, field string, verr *ValidationError) {
+	if field == "" {
+		verr.add(path, "is required")
+		return
+	}
+	if !IsSearchableField(field) {
+		verr.add(path, "unknown field %q, expected one of %v", field, SearchableFields)
+	}
+}
+
+// toQuery converts a validated clause into a bleve query.
+func (c *Clause) toQuery() query.Query {
+	switch {
+	case c.Bool != nil:
+		b := bleve.NewBooleanQuery()
+		for i := range c.Bool.Must {
+			b.AddMust(c.Bool.Must[i].toQuery())
+		}
+		for i := range c.Bool.Should {
+			b.AddShould(c.Bool.Should[i].toQuery())
+		}
+		for i := range c.Bool.MustNot {
+			b.AddMustNot(c.Bool.MustNot[i].toQuery())
+		}
+		if len(c.Bool.Should) > 0 {
+			min := c.Bool.MinimumShouldMatch
+			if min == 0 && len(c.Bool.Must) == 0 {
+				min = 1
+			}
+			b.SetMinShould(float64(min))
+		}
+		return b
+	case c.Match != nil:
+		q := bleve.NewMatchQuery(c.Match.Text)
+		q.SetField(c.Match.Field)
+		if c.Match.Operator == "and" {
+			q.SetOperator(query.MatchQueryOperatorAnd)
+		}
+		return q
+	case c.Phrase != nil:
+		q := bleve.NewMatchPhraseQuery(c.Phrase.Text)
+		q.SetField(c.Phrase.Field)
+		return q
+	case c.Prefix != nil:
+		q := bleve.NewPrefixQuery(strings.ToLower(c.Prefix.Prefix))
+		q.SetField(c.Prefix.Field)
+		return q
+	case c.Fuzzy != nil:
+		q := bleve.NewFuzzyQuery(strings.ToLower(c.Fuzzy.Term))
+		q.SetField(c.Fuzzy.Field)
+		q.SetFuzziness(c.Fuzzy.Fuzziness)
+		return q
+	case c.Range != nil:
+		r := c.Range
+		var min, max string
+		var minInclusive, maxInclusive bool
+		if r.GTE != nil {
+			min, minInclusive = *r.GTE, true
+		} else if r.GT != nil {
+			min = *r.GT
+		}
+		if r.LTE != nil {
+			max, maxInclusive = *r.LTE, true
+		} else if r.LT != nil {
+			max = *r.LT
+		}
+		q := bleve.NewTermRangeInclusiveQuery(min, max, &minInclusive, &maxInclusive)
+		q.SetField(r.Field)
+		return q
+	}
+	return bleve.NewMatchNoneQuery()
+}
+
+// Search runs a validated SearchRequest and returns the requested page. It
+// is cancelled when ctx is done or the search timeout expires.
+func (se *SearchEngine) Search(ctx context.Context, req *SearchRequest) (*SearchResult, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, se.limits.Timeout)
+	defer cancel()
+
+	var q query.Query = bleve.NewMatchAllQuery()
+	if req.Query != nil {
+		q = req.Query.toQuery()
+	}
+
+	search := bleve.NewSearchRequestOptions(q, req.Size, req.From, false)
+	if len(req.Sort) > 0 {
+		search.SortBy(req.Sort)
+	}
+	for name, facet := range req.Facets {
+		search.AddFacet(name, bleve.NewFacetRequest(facet.Field, facet.Size))
+	}
+
+	searchResults, err := se.index.SearchInContext(ctx, search)
+	if err != nil {
+		return nil, fmt.Errorf("error searching index: %w", err)
+	}
+
+	result := &SearchResult{
+		Total: searchResults.Total,
+		From:  req.From,
+		Size:  req.Size,
+		Hits:  []mongo.Patent{},
+	}
+	for _, hit := range searchResults.Hits {
+		patent, err := se.lookupPatent(hit.ID)
+		if errors.Is(err, ErrNotFound) {
+			// Deleted since the search ran
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Hits = append(result.Hits, *patent)
+	}
+
+	if len(req.Facets) > 0 {
+		result.Facets = make(map[string][]FacetCount, len(req.Facets))
+		for name, facetResult := range searchResults.Facets {
+			counts := []FacetCount{}
+			if facetResult.Terms != nil {
+				for _, term := range facetResult.Terms.Terms() {
+					counts = append(counts, FacetCount{Term: term.Term, Count: term.Count})
+				}
+			}
+			result.Facets[name] = counts
+		}
+	}
+	return result, nil
+}
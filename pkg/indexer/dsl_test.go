@@ -0,0 +1,169 @@
+package indexer
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchRequestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// wantPaths are the paths of the expected field errors, in order.
+		wantPaths []string
+	}{
+		{
+			name: "valid",
+			body: `{"query": {"bool": {
+				"must": [{"match": {"field": "PatentTitle", "text": "chair", "operator": "and"}}],
+				"should": [{"prefix": {"field": "AssigneeName", "prefix": "ac"}}],
+				"must_not": [{"range": {"field": "IssueDate", "lt": "20200101"}}]
+			}}, "sort": ["-IssueDate", "_score"], "facets": {"assignees": {"field": "AssigneeName"}}}`,
+		},
+		{
+			name:      "size out of range",
+			body:      `{"size": 101}`,
+			wantPaths: []string{"size"},
+		},
+		{
+			name:      "negative from",
+			body:      `{"from": -1}`,
+			wantPaths: []string{"from"},
+		},
+		{
+			name:      "window exceeded",
+			body:      `{"from": 9995, "size": 10}`,
+			wantPaths: []string{"from"},
+		},
+		{
+			name:      "no clause set",
+			body:      `{"query": {}}`,
+			wantPaths: []string{"query"},
+		},
+		{
+			name:      "two clauses set",
+			body:      `{"query": {"match": {"field": "PatentTitle", "text": "a"}, "phrase": {"field": "PatentTitle", "text": "a"}}}`,
+			wantPaths: []string{"query"},
+		},
+		{
+			name:      "empty bool",
+			body:      `{"query": {"bool": {}}}`,
+			wantPaths: []string{"query.bool"},
+		},
+		{
+			name:      "must_not alone",
+			body:      `{"query": {"bool": {"must_not": [{"match": {"field": "PatentTitle", "text": "a"}}]}}}`,
+			wantPaths: []string{"query.bool"},
+		},
+		{
+			name:      "minimum_should_match too high",
+			body:      `{"query": {"bool": {"should": [{"match": {"field": "PatentTitle", "text": "a"}}], "minimum_should_match": 2}}}`,
+			wantPaths: []string{"query.bool.minimum_should_match"},
+		},
+		{
+			name:      "match errors",
+			body:      `{"query": {"match": {"field": "Abstract", "text": " ", "operator": "xor"}}}`,
+			wantPaths: []string{"query.match.field", "query.match.text", "query.match.operator"},
+		},
+		{
+			name:      "phrase without field",
+			body:      `{"query": {"phrase": {"text": "office chair"}}}`,
+			wantPaths: []string{"query.phrase.field"},
+		},
+		{
+			name:      "short prefix",
+			body:      `{"query": {"prefix": {"field": "PatentTitle", "prefix": "c"}}}`,
+			wantPaths: []string{"query.prefix.prefix"},
+		},
+		{
+			name:      "fuzziness too high",
+			body:      `{"query": {"fuzzy": {"field": "PatentTitle", "term": "chiar", "fuzziness": 3}}}`,
+			wantPaths: []string{"query.fuzzy.fuzziness"},
+		},
+		{
+			name:      "range without bounds",
+			body:      `{"query": {"range": {"field": "IssueDate"}}}`,
+			wantPaths: []string{"query.range"},
+		},
+		{
+			name:      "range with exclusive bounds",
+			body:      `{"query": {"range": {"field": "IssueDate", "gt": "1", "gte": "1", "lt": "2", "lte": "2"}}}`,
+			wantPaths: []string{"query.range", "query.range"},
+		},
+		{
+			name:      "nested error path",
+			body:      `{"query": {"bool": {"must": [{"match": {"field": "PatentTitle", "text": "a"}}, {"prefix": {"field": "PatentTitle", "prefix": "c"}}]}}}`,
+			wantPaths: []string{"query.bool.must[1].prefix.prefix"},
+		},
+		{
+			name:      "too deep",
+			body:      `{"query": {"bool": {"must": [{"bool": {"must": [{"bool": {"must": [{"bool": {"must": [{"bool": {"must": [{"match": {"field": "PatentTitle", "text": "a"}}]}}]}}]}}]}}]}}}`,
+			wantPaths: []string{"query.bool.must[0].bool.must[0].bool.must[0].bool.must[0].bool.must[0]"},
+		},
+		{
+			name:      "unknown sort field",
+			body:      `{"sort": ["-Abstract"]}`,
+			wantPaths: []string{"sort[0]"},
+		},
+		{
+			name:      "facet errors",
+			body:      `{"facets": {"a": {"field": "Abstract", "size": 101}}}`,
+			wantPaths: []string{"facets.a.field", "facets.a.size"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req SearchRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatal(err)
+			}
+			err := req.Validate()
+			if len(tt.wantPaths) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() = %v, want a *ValidationError", err)
+			}
+			var paths []string
+			for _, fe := range verr.Errors {
+				paths = append(paths, fe.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("error paths = %v, want %v", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestSearchRequestValidateTooManyClauses(t *testing.T) {
+	should := strings.Repeat(`{"match": {"field": "PatentTitle", "text": "a"}},`, MaxClauses)
+	body := `{"query": {"bool": {"should": [` + strings.TrimSuffix(should, ",") + `]}}}`
+	var req SearchRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	var verr *ValidationError
+	if err := req.Validate(); !errors.As(err, &verr) || len(verr.Errors) != 1 || verr.Errors[0].Path != "query" {
+		t.Fatalf("Validate() = %v, want a single clause count error", err)
+	}
+}
+
+func TestSearchRequestValidateDefaults(t *testing.T) {
+	req := SearchRequest{Facets: map[string]FacetRequest{"a": {Field: "AssigneeName"}}}
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if req.Size != DefaultSearchSize {
+		t.Errorf("Size = %d, want %d", req.Size, DefaultSearchSize)
+	}
+	if size := req.Facets["a"].Size; size != DefaultSearchSize {
+		t.Errorf("facet size = %d, want %d", size, DefaultSearchSize)
+	}
+}
@@ -0,0 +1,101 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/blevesearch/bleve/v2"
+)
+
+// exportPageSize is the number of hits fetched from the index at a time when
+// exporting.
+const exportPageSize = 500
+
+// PatentCursor iterates over the patents of an export. Next returns io.EOF
+// after the last one.
+type PatentCursor interface {
+	Next() (*mongo.Patent, error)
+}
+
+// ExportCursor iterates over every patent matching a query string search, in
+// document ID order. Matches are fetched a page at a time, so that the result
+// set is never held in memory and the index is only locked while a page is
+// read. The search timeout applies to each page.
+type ExportCursor struct {
+	se         *SearchEngine
+	ctx        context.Context
+	searchTerm string
+
+	page  []mongo.Patent
+	after []string
+	done  bool
+}
+
+// ExportPatents returns a cursor over the patents matching searchTerm.
+// Expensive queries are rejected with a *ValidationError by the first call to
+// Next.
+func (se *SearchEngine) ExportPatents(ctx context.Context, searchTerm string) PatentCursor {
+	return &ExportCursor{se: se, ctx: ctx, searchTerm: searchTerm}
+}
+
+// Next returns the next matching patent, or io.EOF after the last one.
+func (c *ExportCursor) Next() (*mongo.Patent, error) {
+	// A page may be empty when all its patents were deleted since they were
+	// indexed, so keep reading until a patent is found or the hits run out.
+	for len(c.page) == 0 {
+		if c.done {
+			return nil, io.EOF
+		}
+		page, last, hits, err := c.se.exportPage(c.ctx, c.searchTerm, c.after)
+		if err != nil {
+			return nil, err
+		}
+		c.page = page
+		c.after = []string{last}
+		c.done = hits < exportPageSize
+	}
+
+	patent := &c.page[0]
+	c.page = c.page[1:]
+	return patent, nil
+}
+
+// exportPage returns the page of matches following the document ID after,
+// the ID of its last hit and the number of hits. Hits whose patent is no
+// longer stored are skipped.
+func (se *SearchEngine) exportPage(ctx context.Context, searchTerm string, after []string) ([]mongo.Patent, string, int, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	if err := CheckQueryString(searchTerm, se.limits.MaxClauses); err != nil {
+		return nil, "", 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, se.limits.Timeout)
+	defer cancel()
+
+	search := bleve.NewSearchRequestOptions(bleve.NewQueryStringQuery(searchTerm), exportPageSize, 0, false)
+	search.SortBy([]string{"_id"})
+	search.SearchAfter = after
+	searchResults, err := se.index.SearchInContext(ctx, search)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("error searching index: %w", err)
+	}
+
+	patents := make([]mongo.Patent, 0, len(searchResults.Hits))
+	var last string
+	for _, hit := range searchResults.Hits {
+		last = hit.ID
+		patent, err := se.lookupPatent(hit.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, "", 0, err
+		}
+		patents = append(patents, *patent)
+	}
+	return patents, last, len(searchResults.Hits), nil
+}
@@ -0,0 +1,74 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+)
+
+func newTestEngine(t *testing.T, patents ...*mongo.Patent) *SearchEngine {
+	t.Helper()
+	indexMapping, err := NewIndexMapping(&config.AnalyzerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewMemSearchEngine(indexMapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	if err := engine.IndexPatents(patents); err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestExportPatentsSkipsMissing(t *testing.T) {
+	const count = 2*exportPageSize + 10
+	var patents []*mongo.Patent
+	for i := 0; i < count; i++ {
+		patents = append(patents, &mongo.Patent{PatentStorageID: fmt.Sprintf("p%04d", i), PatentTitle: "chair"})
+	}
+	engine := newTestEngine(t, patents...)
+
+	// Drop the stored copy of every patent of the second page and of the
+	// first patent, as a deletion racing the export would.
+	missing := map[string]bool{"p0000": true}
+	for i := exportPageSize; i < 2*exportPageSize; i++ {
+		missing[fmt.Sprintf("p%04d", i)] = true
+	}
+	for id := range missing {
+		if err := engine.index.DeleteInternal([]byte(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cursor := engine.ExportPatents(context.Background(), "chair")
+	var got []string
+	for {
+		patent, err := cursor.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, patent.PatentStorageID)
+	}
+
+	if want := count - len(missing); len(got) != want {
+		t.Fatalf("exported %d patents, want %d", len(got), want)
+	}
+	for i, id := range got {
+		if missing[id] {
+			t.Errorf("exported missing patent %s", id)
+		}
+		if i > 0 && id <= got[i-1] {
+			t.Fatalf("exported %s after %s", id, got[i-1])
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package indexer
+
+import (
+	"strings"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	// DefaultSearchTimeout bounds a search when no timeout is configured.
+	DefaultSearchTimeout = 5 * time.Second
+	// DefaultMaxQueryClauses bounds the terms of a query string when no limit is configured.
+	DefaultMaxQueryClauses = MaxClauses
+)
+
+// QueryLimits bounds the cost of a single search.
+type QueryLimits struct {
+	// Timeout is the longest a search may run before it is cancelled.
+	Timeout time.Duration
+	// MaxClauses is the number of terms and phrases a query string may have.
+	MaxClauses int
+}
+
+// DefaultQueryLimits are the limits of a SearchEngine until SetQueryLimits is called.
+var DefaultQueryLimits = QueryLimits{
+	Timeout:    DefaultSearchTimeout,
+	MaxClauses: DefaultMaxQueryClauses,
+}
+
+// NewQueryLimits reads the query limits from cfg, using the defaults for
+// unset values.
+func NewQueryLimits(cfg *config.ServerConfig) QueryLimits {
+	limits := DefaultQueryLimits
+	if cfg.SearchTimeout > 0 {
+		limits.Timeout = cfg.SearchTimeout
+	}
+	if cfg.SearchMaxClauses > 0 {
+		limits.MaxClauses = cfg.SearchMaxClauses
+	}
+	return limits
+}
+
+// CheckQueryString parses searchTerm and rejects the query string features
+// that can make a search arbitrarily expensive: regular expressions, leading
+// wildcards, large edit distances and more than maxClauses terms. It returns
+// a *ValidationError explaining each problem.
+func CheckQueryString(searchTerm string, maxClauses int) error {
+	verr := &ValidationError{}
+	parsed, err := bleve.NewQueryStringQuery(searchTerm).Parse()
+	if err != nil {
+		verr.add("query", "cannot be parsed: %v", err)
+		return verr
+	}
+
+	clauses := 0
+	checkQuery(parsed, &clauses, verr)
+	if clauses > maxClauses {
+		verr.add("query", "has %d terms, at most %d are allowed", clauses, maxClauses)
+	}
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func checkQuery(q query.Query, clauses *int, verr *ValidationError) {
+	switch q := q.(type) {
+	case *query.BooleanQuery:
+		for _, child := range []query.Query{q.Must, q.Should, q.MustNot} {
+			if child != nil {
+				checkQuery(child, clauses, verr)
+			}
+		}
+	case *query.ConjunctionQuery:
+		for _, child := range q.Conjuncts {
+			checkQuery(child, clauses, verr)
+		}
+	case *query.DisjunctionQuery:
+		for _, child := range q.Disjuncts {
+			checkQuery(child, clauses, verr)
+		}
+	case *query.RegexpQuery:
+		*clauses++
+		verr.add("query", "regular expressions are not allowed: /%s/", q.Regexp)
+	case *query.WildcardQuery:
+		*clauses++
+		if strings.IndexAny(q.Wildcard, "*?") == 0 {
+			verr.add("query", "leading wildcards are not allowed: %s", q.Wildcard)
+		}
+	case *query.FuzzyQuery:
+		*clauses++
+		checkFuzziness(q.Term, q.Fuzziness, verr)
+	case *query.MatchQuery:
+		*clauses++
+		checkFuzziness(q.Match, q.Fuzziness, verr)
+	default:
+		*clauses++
+	}
+}
+
+func checkFuzziness(term string, fuzziness int, verr *ValidationError) {
+	if fuzziness > MaxFuzziness {
+		verr.add("query", "fuzziness of %s is %d, at most %d is allowed", term, fuzziness, MaxFuzziness)
+	}
+}
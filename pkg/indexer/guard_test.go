@@ -0,0 +1,76 @@
+package indexer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/config"
+)
+
+func TestCheckQueryString(t *testing.T) {
+	tests := []struct {
+		name       string
+		searchTerm string
+		maxClauses int
+		// wantErrors are substrings of the expected messages, in order.
+		wantErrors []string
+	}{
+		{"terms", "office chair", 64, nil},
+		{"field and phrase", `PatentTitle:"office chair" +AssigneeName:acme`, 64, nil},
+		{"trailing wildcard", "chai*", 64, nil},
+		{"fuzziness within limit", "chiar~2", 64, nil},
+		{"regexp", "/cha.r/", 64, []string{"regular expressions are not allowed"}},
+		{"field regexp", "PatentTitle:/cha.r/", 64, []string{"regular expressions are not allowed"}},
+		{"leading star", "*hair", 64, []string{"leading wildcards are not allowed"}},
+		{"leading question mark", "?hair", 64, []string{"leading wildcards are not allowed"}},
+		{"fuzziness too high", "chiar~3", 64, []string{"fuzziness of chiar is 3"}},
+		{"several problems", "/cha.r/ *hair chiar~5", 64, []string{"regular expressions", "leading wildcards", "fuzziness of chiar"}},
+		{"clauses at limit", "a b c", 3, nil},
+		{"too many clauses", "a b c d", 3, []string{"has 4 terms, at most 3"}},
+		{"nested clauses counted", "+(a b) -(c d)", 3, []string{"has 4 terms, at most 3"}},
+		{"unparsable", `"office chair`, 64, []string{"cannot be parsed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckQueryString(tt.searchTerm, tt.maxClauses)
+			if len(tt.wantErrors) == 0 {
+				if err != nil {
+					t.Fatalf("CheckQueryString(%q) = %v, want nil", tt.searchTerm, err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("CheckQueryString(%q) = %v, want a *ValidationError", tt.searchTerm, err)
+			}
+			if len(verr.Errors) != len(tt.wantErrors) {
+				t.Fatalf("CheckQueryString(%q) = %v, want %d errors", tt.searchTerm, err, len(tt.wantErrors))
+			}
+			for i, want := range tt.wantErrors {
+				if fe := verr.Errors[i]; fe.Path != "query" || !strings.Contains(fe.Message, want) {
+					t.Errorf("error %d = %s: %s, want query: ...%s...", i, fe.Path, fe.Message, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewQueryLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ServerConfig
+		want QueryLimits
+	}{
+		{"defaults", config.ServerConfig{}, DefaultQueryLimits},
+		{"configured", config.ServerConfig{SearchTimeout: time.Second, SearchMaxClauses: 8}, QueryLimits{Timeout: time.Second, MaxClauses: 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewQueryLimits(&tt.cfg); got != tt.want {
+				t.Errorf("NewQueryLimits() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
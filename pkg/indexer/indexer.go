@@ -1,27 +1,37 @@
 package indexer
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/avyukth/search-app/pkg/database/mongo"
 	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
 )
 
 type SearchEngine struct {
+	// mu guards index, which is replaced when a snapshot is restored.
+	mu    sync.RWMutex
 	index bleve.Index
+	// dir is the index directory, empty for memory-only indexes.
+	dir    string
+	limits QueryLimits
 }
 
-func NewSearchEngine(indexDir string) (*SearchEngine, error) {
+// NewSearchEngine opens the index in indexDir, creating it with indexMapping
+// if it does not exist yet. An existing index keeps the mapping it was created with.
+func NewSearchEngine(indexDir string, indexMapping mapping.IndexMapping) (*SearchEngine, error) {
 	var index bleve.Index
 
 	// Check if the index already exists
 	if _, err := os.Stat(indexDir); errors.Is(err, os.ErrNotExist) {
 		// Create a new index
-		mapping := bleve.NewIndexMapping()
-		index, err = bleve.New(indexDir, mapping)
+		index, err = bleve.New(indexDir, indexMapping)
 		if err != nil {
 			return nil, err
 		}
@@ -35,16 +45,19 @@ func NewSearchEngine(indexDir string) (*SearchEngine, error) {
 		}
 	}
 
-	return &SearchEngine{index: index}, nil
+	return &SearchEngine{index: index, dir: indexDir, limits: DefaultQueryLimits}, nil
 }
 
 func (se *SearchEngine) IndexPatent(patent *mongo.Patent) error {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
 	patentBytes, err := json.Marshal(patent)
 	if err != nil {
 		return fmt.Errorf("error marshalling patent: %v", err)
 	}
 
-	err = se.index.Index(patent.PatentStorageID, patent)
+	err = se.index.Index(patent.PatentStorageID, newPatentDocument(patent))
 	if err != nil {
 		return fmt.Errorf("error adding patent to index: %v", err)
 	}
@@ -57,34 +70,198 @@ func (se *SearchEngine) IndexPatent(patent *mongo.Patent) error {
 	return nil
 }
 
-func (se *SearchEngine) SearchAndRetrievePatents(searchTerm string) ([]mongo.Patent, error) {
+// IndexPatents indexes patents in a single batch, which is much faster than
+// indexing them one at a time. Either every patent is indexed or none is.
+func (se *SearchEngine) IndexPatents(patents []*mongo.Patent) error {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	batch := se.index.NewBatch()
+	for _, patent := range patents {
+		patentBytes, err := json.Marshal(patent)
+		if err != nil {
+			return fmt.Errorf("error marshalling patent: %v", err)
+		}
+		if err := batch.Index(patent.PatentStorageID, newPatentDocument(patent)); err != nil {
+			return fmt.Errorf("error adding patent to batch: %v", err)
+		}
+		batch.SetInternal([]byte(patent.PatentStorageID), patentBytes)
+	}
+
+	if err := se.index.Batch(batch); err != nil {
+		return fmt.Errorf("error adding patents to index: %v", err)
+	}
+	return nil
+}
+
+// SetQueryLimits replaces the limits applied to query string searches.
+func (se *SearchEngine) SetQueryLimits(limits QueryLimits) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	se.limits = limits
+}
+
+// SearchAndRetrievePatents runs a query string search. Expensive queries are
+// rejected with a *ValidationError, and the search is cancelled when ctx is
+// done or the search timeout expires.
+func (se *SearchEngine) SearchAndRetrievePatents(ctx context.Context, searchTerm string) ([]mongo.Patent, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	if err := CheckQueryString(searchTerm, se.limits.MaxClauses); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, se.limits.Timeout)
+	defer cancel()
+
 	query := bleve.NewQueryStringQuery(searchTerm)
 	search := bleve.NewSearchRequest(query)
-	searchResults, err := se.index.Search(search)
+	searchResults, err := se.index.SearchInContext(ctx, search)
 	if err != nil {
-		return nil, fmt.Errorf("error searching index: %v", err)
+		return nil, fmt.Errorf("error searching index: %w", err)
 	}
 
-	var patents []mongo.Patent
+	patents := []mongo.Patent{}
 	for _, hit := range searchResults.Hits {
-		id := hit.ID
-		originalPatentBytes, err := se.index.GetInternal([]byte(id))
+		originalPatent, err := se.lookupPatent(hit.ID)
+		if errors.Is(err, ErrNotFound) {
+			// Deleted since the search ran
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error getting internal patent: %v", err)
+			return nil, err
 		}
+		patents = append(patents, *originalPatent)
+	}
 
-		var originalPatent mongo.Patent
-		err = json.Unmarshal(originalPatentBytes, &originalPatent)
-		if err != nil {
-			return nil, fmt.Errorf("error unmarshalling patent: %v", err)
+	return patents, nil
+}
+
+// DeletePatent removes a patent and its stored copy from the index.
+func (se *SearchEngine) DeletePatent(patentID string) error {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	if err := se.index.Delete(patentID); err != nil {
+		return fmt.Errorf("error deleting patent from index: %v", err)
+	}
+	if err := se.index.DeleteInternal([]byte(patentID)); err != nil {
+		return fmt.Errorf("error deleting internal patent: %v", err)
+	}
+	return nil
+}
+
+// FacetPatents counts the most frequent terms of field among patents matching searchTerm.
+func (se *SearchEngine) FacetPatents(searchTerm, field string, size int) ([]FacetCount, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	search := bleve.NewSearchRequestOptions(bleve.NewQueryStringQuery(searchTerm), 0, 0, false)
+	search.AddFacet(field, bleve.NewFacetRequest(field, size))
+	searchResults, err := se.index.Search(search)
+	if err != nil {
+		return nil, fmt.Errorf("error searching index: %v", err)
+	}
+
+	facets := []FacetCount{}
+	if result, ok := searchResults.Facets[field]; ok && result.Terms != nil {
+		for _, term := range result.Terms.Terms() {
+			facets = append(facets, FacetCount{Term: term.Term, Count: term.Count})
 		}
+	}
+	return facets, nil
+}
+
+// LookupPatent returns the stored copy of an indexed patent.
+func (se *SearchEngine) LookupPatent(patentID string) (*mongo.Patent, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	return se.lookupPatent(patentID)
+}
 
-		patents = append(patents, originalPatent)
+func (se *SearchEngine) lookupPatent(patentID string) (*mongo.Patent, error) {
+	patentBytes, err := se.index.GetInternal([]byte(patentID))
+	if err != nil {
+		return nil, fmt.Errorf("error getting internal patent: %v", err)
+	}
+	if patentBytes == nil {
+		return nil, fmt.Errorf("patent %s: %w", patentID, ErrNotFound)
 	}
 
-	if len(patents) == 0 {
-		return nil, fmt.Errorf("no patents found for search term: %s", searchTerm)
+	var patent mongo.Patent
+	if err := json.Unmarshal(patentBytes, &patent); err != nil {
+		return nil, fmt.Errorf("error unmarshalling patent: %v", err)
 	}
+	return &patent, nil
+}
 
-	return patents, nil
+// DocCount returns the number of indexed patents. It fails once the index is
+// closed, such as after a failed restore.
+func (se *SearchEngine) DocCount() (uint64, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	return se.index.DocCount()
+}
+
+// Close releases the underlying index.
+func (se *SearchEngine) Close() error {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	return se.index.Close()
+}
+
+// SearchableFields lists the patent fields that can be targeted by filters.
+var SearchableFields = []string{
+	"PatentTitle",
+	"PatentNumber",
+	"InventorNames",
+	"AssigneeName",
+	"ApplicationDate",
+	"IssueDate",
+	"DesignClass",
+}
+
+// IsSearchableField reports whether field is one of SearchableFields.
+func IsSearchableField(field string) bool {
+	for _, f := range SearchableFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateQueryString checks that searchTerm parses as a query string query
+// within the default query limits.
+func ValidateQueryString(searchTerm string) error {
+	return CheckQueryString(searchTerm, DefaultMaxQueryClauses)
+}
+
+// MatchesPatent reports whether the indexed patent with the given ID matches
+// searchTerm and every field filter.
+func (se *SearchEngine) MatchesPatent(patentID, searchTerm string, filters map[string]string) (bool, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	conjunction := bleve.NewConjunctionQuery(bleve.NewDocIDQuery([]string{patentID}))
+	if searchTerm != "" {
+		conjunction.AddQuery(bleve.NewQueryStringQuery(searchTerm))
+	}
+	for field, value := range filters {
+		match := bleve.NewMatchQuery(value)
+		match.SetField(field)
+		match.SetOperator(query.MatchQueryOperatorAnd)
+		conjunction.AddQuery(match)
+	}
+
+	search := bleve.NewSearchRequestOptions(conjunction, 1, 0, false)
+	searchResults, err := se.index.Search(search)
+	if err != nil {
+		return false, fmt.Errorf("error searching index: %v", err)
+	}
+	return searchResults.Total > 0, nil
 }
@@ -0,0 +1,283 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/antzucaro/matchr"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// Inventor match types, from most to least certain.
+const (
+	InventorMatchExact    = "exact"
+	InventorMatchFuzzy    = "fuzzy"
+	InventorMatchPhonetic = "phonetic"
+)
+
+// Weights of the last and given name in an inventor match confidence.
+const (
+	lastNameWeight  = 0.7
+	givenNameWeight = 0.3
+
+	// phoneticConfidence is the last name score of names that only sound alike.
+	phoneticConfidence = 0.75
+	// initialConfidence is the given name score of an initial matching a name.
+	initialConfidence = 0.85
+)
+
+// InventorName is an inventor name split into its last name and given names.
+// Given names of a single letter are initials.
+type InventorName struct {
+	Last  string   `json:"last"`
+	Given []string `json:"given"`
+
+	full string
+}
+
+// InventorMatch is a patent found by inventor search together with the
+// inventor that matched best and how confident the match is.
+type InventorMatch struct {
+	Patent     mongo.Patent `json:"patent"`
+	Inventor   string       `json:"inventor"`
+	MatchType  string       `json:"matchType"`
+	Confidence float64      `json:"confidence"`
+}
+
+// ParseInventorName splits "Last, First M." or "First M. Last" into its parts.
+// Names are lower cased and stripped of punctuation.
+func ParseInventorName(name string) InventorName {
+	if last, given, ok := strings.Cut(name, ","); ok {
+		return InventorName{Last: strings.Join(nameWords(last), " "), Given: nameWords(given)}
+	}
+	words := nameWords(name)
+	if len(words) == 0 {
+		return InventorName{}
+	}
+	return InventorName{Last: words[len(words)-1], Given: words[:len(words)-1]}
+}
+
+func nameWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ' ' || r == '.' || r == ',' || r == '\t'
+	})
+}
+
+// inventorNames returns the parsed names of the patent inventors, falling back
+// to InventorNames for patents stored before inventors were kept separately.
+func inventorNames(patent *mongo.Patent) []InventorName {
+	var names []InventorName
+	if len(patent.Inventors) > 0 {
+		for _, inventor := range patent.Inventors {
+			names = append(names, InventorName{
+				Last:  strings.Join(nameWords(inventor.LastName), " "),
+				Given: nameWords(inventor.FirstName),
+				full:  inventor.FirstName + " " + inventor.LastName,
+			})
+		}
+		return names
+	}
+	for _, name := range patent.InventorNames {
+		parsed := ParseInventorName(name)
+		parsed.full = name
+		names = append(names, parsed)
+	}
+	return names
+}
+
+// phoneticCodes returns the Double Metaphone codes of every word of name.
+func phoneticCodes(name string) []string {
+	var codes []string
+	for _, word := range strings.Fields(name) {
+		primary, secondary := matchr.DoubleMetaphone(word)
+		if primary != "" {
+			codes = append(codes, primary)
+		}
+		if secondary != "" && secondary != primary {
+			codes = append(codes, secondary)
+		}
+	}
+	return codes
+}
+
+// nameFuzziness is the edit distance tolerated for a name of the given length.
+func nameFuzziness(name string) int {
+	switch n := len([]rune(name)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func addInventorMappings(indexMapping *mapping.IndexMappingImpl) error {
+	lastName := bleve.NewTextFieldMapping()
+	lastName.Analyzer = PlainAnalyzer
+	lastName.IncludeInAll = false
+	lastName.IncludeTermVectors = false
+	lastName.Store = false
+	indexMapping.DefaultMapping.AddFieldMappingsAt("InventorLast", lastName)
+
+	phonetic := bleve.NewTextFieldMapping()
+	phonetic.Analyzer = keyword.Name
+	phonetic.IncludeInAll = false
+	phonetic.IncludeTermVectors = false
+	phonetic.Store = false
+	indexMapping.DefaultMapping.AddFieldMappingsAt("InventorPhonetic", phonetic)
+	return nil
+}
+
+// SearchInventors finds patents with an inventor whose last name is within a
+// few edits of, or sounds like, the last name in name. Given names and
+// initials only affect the confidence of each match. Results are ordered by
+// confidence.
+func (se *SearchEngine) SearchInventors(name string, size int) ([]InventorMatch, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	parsed := ParseInventorName(name)
+	if parsed.Last == "" {
+		return nil, fmt.Errorf("inventor name %q has no last name", name)
+	}
+
+	var lastNameQueries []query.Query
+	for _, word := range strings.Fields(parsed.Last) {
+		fuzzy := bleve.NewFuzzyQuery(word)
+		fuzzy.SetField("InventorLast")
+		fuzzy.SetFuzziness(nameFuzziness(word))
+		lastNameQueries = append(lastNameQueries, fuzzy)
+	}
+	for _, code := range phoneticCodes(parsed.Last) {
+		term := bleve.NewTermQuery(code)
+		term.SetField("InventorPhonetic")
+		lastNameQueries = append(lastNameQueries, term)
+	}
+
+	// Fetch more hits than requested, since the ranking below uses the
+	// confidence rather than the bleve score.
+	search := bleve.NewSearchRequestOptions(bleve.NewDisjunctionQuery(lastNameQueries...), size*2, 0, false)
+	searchResults, err := se.index.Search(search)
+	if err != nil {
+		return nil, fmt.Errorf("error searching index: %v", err)
+	}
+
+	matches := []InventorMatch{}
+	for _, hit := range searchResults.Hits {
+		patent, err := se.lookupPatent(hit.ID)
+		if errors.Is(err, ErrNotFound) {
+			// Deleted since the search ran
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		best := InventorMatch{Patent: *patent}
+		for _, candidate := range inventorNames(patent) {
+			confidence, matchType := scoreInventor(parsed, candidate)
+			if confidence > best.Confidence {
+				best.Confidence = confidence
+				best.MatchType = matchType
+				best.Inventor = candidate.full
+			}
+		}
+		if best.Confidence > 0 {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Confidence > matches[j].Confidence })
+	if len(matches) > size {
+		matches = matches[:size]
+	}
+	return matches, nil
+}
+
+// scoreInventor returns the confidence, between 0 and 1, that candidate is the
+// inventor named by want, and how the last names matched.
+func scoreInventor(want, candidate InventorName) (float64, string) {
+	var lastScore float64
+	matchType := InventorMatchExact
+	if want.Last == candidate.Last {
+		lastScore = 1
+	} else {
+		distance := matchr.DamerauLevenshtein(want.Last, candidate.Last)
+		if distance <= nameFuzziness(want.Last) {
+			lastScore = similarity(want.Last, candidate.Last, distance)
+			matchType = InventorMatchFuzzy
+		}
+		if lastScore < phoneticConfidence && soundAlike(want.Last, candidate.Last) {
+			lastScore = phoneticConfidence
+			matchType = InventorMatchPhonetic
+		}
+	}
+	if lastScore == 0 {
+		return 0, ""
+	}
+
+	givenScore := 1.0
+	if len(want.Given) > 0 {
+		var total float64
+		for _, w := range want.Given {
+			total += bestGivenScore(w, candidate.Given)
+		}
+		givenScore = total / float64(len(want.Given))
+	}
+
+	confidence := lastNameWeight*lastScore + givenNameWeight*givenScore
+	return math.Round(confidence*100) / 100, matchType
+}
+
+func bestGivenScore(want string, given []string) float64 {
+	var best float64
+	for _, g := range given {
+		var score float64
+		switch {
+		case want == g:
+			score = 1
+		case len(want) == 1 || len(g) == 1:
+			if want[0] == g[0] {
+				score = initialConfidence
+			}
+		default:
+			distance := matchr.DamerauLevenshtein(want, g)
+			if distance <= nameFuzziness(want) {
+				score = similarity(want, g, distance)
+			} else if soundAlike(want, g) {
+				score = phoneticConfidence
+			}
+		}
+		if score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+func similarity(a, b string, distance int) float64 {
+	longest := len([]rune(a))
+	if n := len([]rune(b)); n > longest {
+		longest = n
+	}
+	return 1 - float64(distance)/float64(longest)
+}
+
+func soundAlike(a, b string) bool {
+	for _, x := range phoneticCodes(a) {
+		for _, y := range phoneticCodes(b) {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// MappingVersion identifies the layout produced by NewIndexMapping. It is
+// recorded in snapshots and must be bumped whenever a mapping change requires
+// patents to be reindexed.
+const MappingVersion = 1
+
+// ErrSnapshotUnsupported is returned by backends that cannot copy their index.
+var ErrSnapshotUnsupported = errors.New("index snapshots are not supported by this backend")
+
+// Snapshot writes a consistent point-in-time copy of the index into dir.
+// Indexing continues while the copy is made.
+func (se *SearchEngine) Snapshot(dir string) error {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	if se.dir == "" {
+		return fmt.Errorf("memory index: %w", ErrSnapshotUnsupported)
+	}
+	copyable, ok := se.index.(bleve.IndexCopyable)
+	if !ok {
+		return ErrSnapshotUnsupported
+	}
+	if err := copyable.CopyTo(bleve.FileSystemDirectory(dir)); err != nil {
+		return fmt.Errorf("error copying index: %v", err)
+	}
+	return nil
+}
+
+// Restore replaces the index with the copy in srcDir, as written by Snapshot.
+// The copy is checked before the current index is closed; searches wait while
+// the directories are swapped.
+func (se *SearchEngine) Restore(srcDir string) error {
+	if se.dir == "" {
+		return fmt.Errorf("memory index: %w", ErrSnapshotUnsupported)
+	}
+
+	staging := se.dir + ".restore"
+	if err := os.RemoveAll(staging); err != nil {
+		return fmt.Errorf("error clearing %s: %v", staging, err)
+	}
+	if err := copyDir(srcDir, staging); err != nil {
+		os.RemoveAll(staging)
+		return fmt.Errorf("error staging snapshot: %v", err)
+	}
+	restored, err := bleve.Open(staging)
+	if err != nil {
+		os.RemoveAll(staging)
+		return fmt.Errorf("error opening snapshot: %v", err)
+	}
+	if err := restored.Close(); err != nil {
+		os.RemoveAll(staging)
+		return fmt.Errorf("error closing snapshot: %v", err)
+	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if err := se.index.Close(); err != nil {
+		return fmt.Errorf("error closing index: %v", err)
+	}
+
+	previous := se.dir + ".previous"
+	if err := os.RemoveAll(previous); err != nil {
+		return se.reopen(fmt.Errorf("error clearing %s: %v", previous, err))
+	}
+	if err := os.Rename(se.dir, previous); err != nil {
+		return se.reopen(fmt.Errorf("error moving index aside: %v", err))
+	}
+	if err := os.Rename(staging, se.dir); err != nil {
+		os.Rename(previous, se.dir)
+		return se.reopen(fmt.Errorf("error moving snapshot into place: %v", err))
+	}
+
+	index, err := bleve.Open(se.dir)
+	if err != nil {
+		os.RemoveAll(se.dir)
+		os.Rename(previous, se.dir)
+		return se.reopen(fmt.Errorf("error opening restored index: %v", err))
+	}
+	se.index = index
+	if err := os.RemoveAll(previous); err != nil {
+		return fmt.Errorf("error removing previous index: %v", err)
+	}
+	return nil
+}
+
+// reopen opens the index directory again after a failed restore and returns
+// cause, joined with the error of reopening if that failed too.
+func (se *SearchEngine) reopen(cause error) error {
+	index, err := bleve.Open(se.dir)
+	if err != nil {
+		return errors.Join(cause, fmt.Errorf("error reopening index: %v", err))
+	}
+	se.index = index
+	return cause
+}
+
+// copyDir copies the regular files under src into dst, creating dst.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
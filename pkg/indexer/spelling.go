@@ -0,0 +1,181 @@
+package indexer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/antzucaro/matchr"
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	index "github.com/blevesearch/bleve_index_api"
+)
+
+const (
+	// spellingField holds the unstemmed words of titles, assignees and
+	// inventors; its term dictionary is the vocabulary for corrections.
+	spellingField = "Spelling"
+
+	maxSpellingCandidates = 5
+	minSpellingWordLength = 3
+)
+
+// queryWord matches the words of a query string that are candidates for
+// correction. Field names, operators and words with wildcards are skipped by
+// spellingWords.
+var queryWord = regexp.MustCompile(`[\p{L}][\p{L}\p{N}']*`)
+
+// SpellingCandidate is an indexed word close to a misspelled query word.
+type SpellingCandidate struct {
+	Text  string `json:"text"`
+	Count uint64 `json:"count"`
+}
+
+// SpellingCorrection lists the candidates for one query word that is not in
+// the index, best first.
+type SpellingCorrection struct {
+	Term       string              `json:"term"`
+	Candidates []SpellingCandidate `json:"candidates"`
+}
+
+// SpellingResult holds the corrections for a query and the query rewritten
+// with the best candidate for each misspelled word. DidYouMean is empty when
+// no word could be corrected.
+type SpellingResult struct {
+	Corrections []SpellingCorrection `json:"corrections"`
+	DidYouMean  string               `json:"didYouMean,omitempty"`
+}
+
+func addSpellingMappings(indexMapping *mapping.IndexMappingImpl) error {
+	spelling := bleve.NewTextFieldMapping()
+	spelling.Analyzer = PlainAnalyzer
+	spelling.IncludeInAll = false
+	spelling.IncludeTermVectors = false
+	spelling.Store = false
+	indexMapping.DefaultMapping.AddFieldMappingsAt(spellingField, spelling)
+	return nil
+}
+
+type wordSpan struct {
+	start, end int
+	word       string
+}
+
+// spellingWords returns the words of searchTerm that should be spell checked.
+func spellingWords(searchTerm string) []wordSpan {
+	var words []wordSpan
+	for _, loc := range queryWord.FindAllStringIndex(searchTerm, -1) {
+		start, end := loc[0], loc[1]
+		word := searchTerm[start:end]
+		if len([]rune(word)) < minSpellingWordLength {
+			continue
+		}
+		if word == "AND" || word == "OR" || word == "NOT" {
+			continue
+		}
+		if end < len(searchTerm) && strings.ContainsRune(":*?~^", rune(searchTerm[end])) {
+			continue
+		}
+		if start > 0 && strings.ContainsRune("*?/", rune(searchTerm[start-1])) {
+			continue
+		}
+		words = append(words, wordSpan{start: start, end: end, word: strings.ToLower(word)})
+	}
+	return words
+}
+
+// SuggestSpelling proposes corrections for the words of searchTerm that do not
+// appear in the index, using the index term dictionary as vocabulary.
+func (se *SearchEngine) SuggestSpelling(searchTerm string) (*SpellingResult, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	advanced, err := se.index.Advanced()
+	if err != nil {
+		return nil, fmt.Errorf("error accessing index: %v", err)
+	}
+	reader, err := advanced.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("error opening index reader: %v", err)
+	}
+	defer reader.Close()
+
+	result := &SpellingResult{Corrections: []SpellingCorrection{}}
+	var corrected strings.Builder
+	last := 0
+	for _, span := range spellingWords(searchTerm) {
+		candidates, known, err := spellingCandidates(reader, span.word)
+		if err != nil {
+			return nil, err
+		}
+		if known || len(candidates) == 0 {
+			continue
+		}
+
+		result.Corrections = append(result.Corrections, SpellingCorrection{Term: span.word, Candidates: candidates})
+		corrected.WriteString(searchTerm[last:span.start])
+		corrected.WriteString(candidates[0].Text)
+		last = span.end
+	}
+
+	if len(result.Corrections) > 0 {
+		corrected.WriteString(searchTerm[last:])
+		result.DidYouMean = corrected.String()
+	}
+	return result, nil
+}
+
+// spellingCandidates returns the indexed words within two edits of word,
+// closest and most frequent first, and whether word itself is indexed.
+func spellingCandidates(reader index.IndexReader, word string) ([]SpellingCandidate, bool, error) {
+	var dict index.FieldDict
+	var err error
+	if fuzzy, ok := reader.(index.IndexReaderFuzzy); ok {
+		dict, err = fuzzy.FieldDictFuzzy(spellingField, word, MaxFuzziness, "")
+	} else {
+		// Readers without fuzzy dictionaries are scanned from the first
+		// letter, which spelling mistakes rarely affect.
+		first := []rune(word)[0]
+		dict, err = reader.FieldDictPrefix(spellingField, []byte(string(first)))
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading term dictionary: %v", err)
+	}
+	defer dict.Close()
+
+	type scored struct {
+		SpellingCandidate
+		distance int
+	}
+	var found []scored
+	for {
+		entry, err := dict.Next()
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading term dictionary: %v", err)
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Term == word {
+			return nil, true, nil
+		}
+		distance := matchr.DamerauLevenshtein(word, entry.Term)
+		if distance > MaxFuzziness {
+			continue
+		}
+		found = append(found, scored{SpellingCandidate{Text: entry.Term, Count: entry.Count}, distance})
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+		return found[i].Count > found[j].Count
+	})
+	candidates := []SpellingCandidate{}
+	for i := 0; i < len(found) && i < maxSpellingCandidates; i++ {
+		candidates = append(candidates, found[i].SpellingCandidate)
+	}
+	return candidates, false, nil
+}
@@ -0,0 +1,102 @@
+package indexer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+)
+
+func TestSpellingWords(t *testing.T) {
+	tests := []struct {
+		searchTerm string
+		want       []string
+	}{
+		{"Office Chiar", []string{"office", "chiar"}},
+		{"chair AND table OR NOT desk", []string{"chair", "table", "desk"}},
+		{"PatentTitle:chair", []string{"chair"}},
+		{"chai* ch?ir chiar~1 chair^2", nil},
+		{"*chair /cha.r/", nil},
+		{"a to the", []string{"the"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.searchTerm, func(t *testing.T) {
+			var got []string
+			for _, span := range spellingWords(tt.searchTerm) {
+				got = append(got, span.word)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("spellingWords(%q) = %v, want %v", tt.searchTerm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestSpelling(t *testing.T) {
+	engine := newTestEngine(t,
+		&mongo.Patent{PatentStorageID: "1", PatentTitle: "Office chair", AssigneeName: "Acme"},
+		&mongo.Patent{PatentStorageID: "2", PatentTitle: "Folding chair"},
+		&mongo.Patent{PatentStorageID: "3", PatentTitle: "Chain guard"},
+	)
+
+	tests := []struct {
+		name           string
+		searchTerm     string
+		wantTerms      []string
+		wantFirst      []string
+		wantDidYouMean string
+	}{
+		{
+			name:       "known words",
+			searchTerm: "office chair",
+		},
+		{
+			name:           "misspelled word",
+			searchTerm:     "ofice chair",
+			wantTerms:      []string{"ofice"},
+			wantFirst:      []string{"office"},
+			wantDidYouMean: "office chair",
+		},
+		{
+			name:           "more frequent candidate first",
+			searchTerm:     "PatentTitle:chaim AND acme",
+			wantTerms:      []string{"chaim"},
+			wantFirst:      []string{"chair"},
+			wantDidYouMean: "PatentTitle:chair AND acme",
+		},
+		{
+			name:           "transposition",
+			searchTerm:     "foldnig chiar",
+			wantTerms:      []string{"foldnig", "chiar"},
+			wantFirst:      []string{"folding", "chair"},
+			wantDidYouMean: "folding chair",
+		},
+		{
+			name:       "no candidate",
+			searchTerm: "xylophone",
+		},
+		{
+			name:       "wildcard skipped",
+			searchTerm: "ofice*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.SuggestSpelling(tt.searchTerm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var terms, first []string
+			for _, correction := range result.Corrections {
+				terms = append(terms, correction.Term)
+				first = append(first, correction.Candidates[0].Text)
+			}
+			if !reflect.DeepEqual(terms, tt.wantTerms) || !reflect.DeepEqual(first, tt.wantFirst) {
+				t.Errorf("corrections = %v to %v, want %v to %v", terms, first, tt.wantTerms, tt.wantFirst)
+			}
+			if result.DidYouMean != tt.wantDidYouMean {
+				t.Errorf("DidYouMean = %q, want %q", result.DidYouMean, tt.wantDidYouMean)
+			}
+		})
+	}
+}
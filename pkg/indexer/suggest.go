@@ -0,0 +1,174 @@
+package indexer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/char/asciifolding"
+	"github.com/blevesearch/bleve/v2/analysis/token/edgengram"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	// SuggestAnalyzer indexes every prefix of every word of a suggestion field.
+	SuggestAnalyzer = "patent_suggest"
+
+	suggestEdgeNgram   = "patent_edge_ngram"
+	maxSuggestPrefix   = 20
+	DefaultSuggestions = 5
+	MaxSuggestions     = 20
+
+	// suggestOverfetch widens the facet so that co-inventors that do not match
+	// the typed text can be dropped without running short of suggestions.
+	suggestOverfetch = 4
+)
+
+// suggestField pairs the prefix-matched field of a suggestion source with the
+// keyword field whose values are returned.
+type suggestField struct {
+	prefix string
+	term   string
+}
+
+// SuggestFields maps the names accepted by Suggest to their index fields.
+var SuggestFields = map[string]suggestField{
+	"title":    {prefix: "TitleSuggest", term: "TitleTerm"},
+	"assignee": {prefix: "AssigneeSuggest", term: "AssigneeTerm"},
+	"inventor": {prefix: "InventorSuggest", term: "InventorTerm"},
+}
+
+// Suggestion is a distinct field value and the number of patents having it.
+type Suggestion struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+func addSuggestMappings(indexMapping *mapping.IndexMappingImpl) error {
+	if err := indexMapping.AddCustomTokenFilter(suggestEdgeNgram, map[string]interface{}{
+		"type": edgengram.Name,
+		"min":  1.0,
+		"max":  float64(maxSuggestPrefix),
+	}); err != nil {
+		return err
+	}
+	if err := indexMapping.AddCustomAnalyzer(SuggestAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"char_filters":  []interface{}{asciifolding.Name},
+		"tokenizer":     unicode.Name,
+		"token_filters": []interface{}{lowercase.Name, suggestEdgeNgram},
+	}); err != nil {
+		return err
+	}
+
+	for _, field := range SuggestFields {
+		prefixField := bleve.NewTextFieldMapping()
+		prefixField.Analyzer = SuggestAnalyzer
+		prefixField.IncludeInAll = false
+		prefixField.IncludeTermVectors = false
+		prefixField.Store = false
+		indexMapping.DefaultMapping.AddFieldMappingsAt(field.prefix, prefixField)
+
+		termField := bleve.NewTextFieldMapping()
+		termField.Analyzer = keyword.Name
+		termField.IncludeInAll = false
+		termField.IncludeTermVectors = false
+		termField.Store = false
+		indexMapping.DefaultMapping.AddFieldMappingsAt(field.term, termField)
+	}
+	return nil
+}
+
+// Suggest returns, for each requested field, the most common values that have
+// a word starting with every word of text. Text is split into words by PlainAnalyzer.
+func (se *SearchEngine) Suggest(text string, fields []string, size int) (map[string][]Suggestion, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	analyzer := se.index.Mapping().AnalyzerNamed(PlainAnalyzer)
+	if analyzer == nil {
+		return nil, fmt.Errorf("index mapping has no %s analyzer: %w", PlainAnalyzer, ErrInvalidAnalyzer)
+	}
+	var words []string
+	for _, token := range analyzer.Analyze([]byte(text)) {
+		word := string(token.Term)
+		if runes := []rune(word); len(runes) > maxSuggestPrefix {
+			word = string(runes[:maxSuggestPrefix])
+		}
+		words = append(words, word)
+	}
+
+	suggestions := make(map[string][]Suggestion, len(fields))
+	if len(words) == 0 {
+		for _, name := range fields {
+			suggestions[name] = []Suggestion{}
+		}
+		return suggestions, nil
+	}
+
+	search := bleve.NewSearchRequestOptions(nil, 0, 0, false)
+	var disjunction []query.Query
+	for _, name := range fields {
+		field, ok := SuggestFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown suggestion field %q", name)
+		}
+		conjunction := bleve.NewConjunctionQuery()
+		for _, word := range words {
+			term := bleve.NewTermQuery(word)
+			term.SetField(field.prefix)
+			conjunction.AddQuery(term)
+		}
+		disjunction = append(disjunction, conjunction)
+		search.AddFacet(name, bleve.NewFacetRequest(field.term, size*suggestOverfetch))
+	}
+	search.Query = bleve.NewDisjunctionQuery(disjunction...)
+
+	searchResults, err := se.index.Search(search)
+	if err != nil {
+		return nil, fmt.Errorf("error searching index: %v", err)
+	}
+
+	for _, name := range fields {
+		result := []Suggestion{}
+		if facet, ok := searchResults.Facets[name]; ok && facet.Terms != nil {
+			for _, term := range facet.Terms.Terms() {
+				if !matchesPrefixes(analyzer, term.Term, words) {
+					continue
+				}
+				result = append(result, Suggestion{Text: term.Term, Count: term.Count})
+			}
+		}
+		sort.SliceStable(result, func(i, j int) bool { return result[i].Count > result[j].Count })
+		if len(result) > size {
+			result = result[:size]
+		}
+		suggestions[name] = result
+	}
+	return suggestions, nil
+}
+
+// matchesPrefixes reports whether every word is a prefix of a word of value.
+func matchesPrefixes(analyzer analysis.Analyzer, value string, words []string) bool {
+	tokens := analyzer.Analyze([]byte(value))
+	for _, word := range words {
+		found := false
+		for _, token := range tokens {
+			if strings.HasPrefix(string(token.Term), word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
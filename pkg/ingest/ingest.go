@@ -0,0 +1,200 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/downloader"
+	"github.com/avyukth/search-app/pkg/queue"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ingestion source types.
+const (
+	TypeDownload = "download"
+	TypeCrawl    = "crawl"
+)
+
+// MaxIdempotencyKeyLength bounds the keys stored in MongoDB.
+const MaxIdempotencyKeyLength = 255
+
+// Reasons for refusing an ingestion request. Links that are not live are
+// refused with downloader.ErrLinkNotLive, and full or draining queues with
+// queue.ErrFull or queue.ErrDraining.
+var (
+	ErrAlreadyProcessed = errors.New("Link is already processed or completed")
+	// ErrKeyReused is returned when an idempotency key comes back with a
+	// different request.
+	ErrKeyReused = errors.New("idempotency key was already used for a different request")
+	// ErrKeyInProgress is returned when an idempotency key comes back before
+	// the job of its first request was created.
+	ErrKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+type Request struct {
+	// Type is the source of the ingestion: "download" fetches URL, "crawl"
+	// walks Path on the server.
+	Type    string  `json:"type" validate:"required" enums:"download,crawl"`
+	URL     string  `json:"url,omitempty"`
+	Path    string  `json:"path,omitempty"`
+	Options Options `json:"options"`
+}
+
+type Options struct {
+	// Force downloads a link again even if it was ingested before.
+	Force bool `json:"force,omitempty"`
+}
+
+func (r *Request) Validate() error {
+	switch r.Type {
+	case TypeDownload:
+		link, err := url.Parse(r.URL)
+		if r.URL == "" || err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+			return errors.New("url must be an absolute http or https URL for download ingestions")
+		}
+		if r.Path != "" {
+			return errors.New("path is not allowed for download ingestions")
+		}
+	case TypeCrawl:
+		if r.Path == "" {
+			return errors.New("path is required for crawl ingestions")
+		}
+		if r.URL != "" {
+			return errors.New("url is not allowed for crawl ingestions")
+		}
+		if r.Options.Force {
+			return errors.New("options.force only applies to download ingestions")
+		}
+	default:
+		return fmt.Errorf("unknown type %q, expected %q or %q", r.Type, TypeDownload, TypeCrawl)
+	}
+	return nil
+}
+
+// Hash identifies the request, so that an idempotency key reused for another
+// request is detected.
+func (r *Request) Hash() string {
+	body, _ := json.Marshal(r)
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Result is the job started by an ingestion request.
+type Result struct {
+	JobID string
+	// Replayed is set when the idempotency key was seen before, in which case
+	// Job is the job of the first request.
+	Replayed bool
+	Job      *mongo.Job
+}
+
+// Submitter starts ingestion jobs for the HTTP and gRPC APIs. Links are
+// checked with client before their job starts.
+type Submitter struct {
+	db     *mongo.Database
+	q      *queue.TaskQueue
+	client *http.Client
+}
+
+func NewSubmitter(db *mongo.Database, q *queue.TaskQueue, client *http.Client) *Submitter {
+	return &Submitter{db: db, q: q, client: client}
+}
+
+// Submit creates the job of a validated request and enqueues its task. A
+// request sent again by the same API key with the same non-empty key returns
+// the job of the first request instead of starting another one. apiKeyID is
+// empty when authentication is disabled. ctx bounds the check of the link of
+// a download.
+func (s *Submitter) Submit(ctx context.Context, req *Request, apiKeyID, key string) (*Result, error) {
+	jobID := primitive.NewObjectID()
+	if key != "" {
+		existing, err := s.db.ReserveIdempotencyKey(&mongo.IdempotencyKey{APIKeyID: apiKeyID, Key: key, RequestHash: req.Hash(), JobID: jobID.Hex()})
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return s.replay(existing, req.Hash())
+		}
+	}
+
+	if err := s.submit(ctx, req, jobID); err != nil {
+		// The request did not start a job, so its key may be used again.
+		if key != "" {
+			if err := s.db.DeleteIdempotencyKey(apiKeyID, key); err != nil {
+				log.Printf("Error releasing idempotency key: %v", err)
+			}
+		}
+		return nil, err
+	}
+	return &Result{JobID: jobID.Hex()}, nil
+}
+
+// replay returns the job of the first request sent with an idempotency key.
+func (s *Submitter) replay(existing *mongo.IdempotencyKey, requestHash string) (*Result, error) {
+	if existing.RequestHash != requestHash {
+		return nil, ErrKeyReused
+	}
+	job, err := s.db.RetrieveJob(existing.JobID)
+	if errors.Is(err, mongo.ErrNotFound) {
+		return nil, ErrKeyInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Result{JobID: existing.JobID, Replayed: true, Job: job}, nil
+}
+
+func (s *Submitter) submit(ctx context.Context, req *Request, jobID primitive.ObjectID) error {
+	task := queue.Task{JobID: jobID.Hex()}
+	job := &mongo.Job{ID: jobID, Type: req.Type}
+
+	switch req.Type {
+	case TypeDownload:
+		if err := downloader.CheckLink(ctx, s.client, req.URL); err != nil {
+			return err
+		}
+
+		if !req.Options.Force {
+			// Check and set link status in MongoDB
+			isSet, err := s.db.CheckAndSetLinkStatus(req.URL)
+			if err != nil {
+				return fmt.Errorf("error processing link: %w", err)
+			}
+			if !isSet {
+				return ErrAlreadyProcessed
+			}
+		}
+		job.Source = req.URL
+		task.FilePath = req.URL
+		task.Type = queue.DownloadAndProcess
+	case TypeCrawl:
+		job.Source = req.Path
+		task.FilePath = req.Path
+		task.Type = queue.WalkAndProcess
+	}
+
+	if _, err := s.db.StoreJob(job); err != nil {
+		return fmt.Errorf("error creating job: %w", err)
+	}
+	if err := s.q.Enqueue(task); err != nil {
+		// The job never runs, so it is failed and its link may be sent again.
+		if err := s.db.UpdateJobState(task.JobID, mongo.JobFailed, err); err != nil {
+			log.Printf("Error failing job %s: %v", task.JobID, err)
+		}
+		if req.Type == TypeDownload && !req.Options.Force {
+			if err := s.db.ReleaseLinkStatus(req.URL); err != nil {
+				log.Printf("Error releasing link %s: %v", req.URL, err)
+			}
+		}
+		return err
+	}
+	return nil
+}
@@ -13,14 +13,12 @@ import (
 )
 
 type Parser struct {
-	errCh chan error
-	wg    *sync.WaitGroup
+	wg *sync.WaitGroup
 }
 
 func NewParser() *Parser {
 	return &Parser{
-		errCh: make(chan error),
-		wg:    &sync.WaitGroup{},
+		wg: &sync.WaitGroup{},
 	}
 }
 
@@ -28,14 +26,12 @@ func (p *Parser) Parse(filePath string) (map[string]interface{}, error) {
 	xmlData, err := os.ReadFile(filePath)
 
 	if err != nil {
-		p.errCh <- fmt.Errorf("Error reading XML file %s: %v", filePath, err)
-		return nil, err
+		return nil, fmt.Errorf("error reading XML file %s: %w", filePath, err)
 	}
 
 	mv, err := mxj.NewMapXml(xmlData)
 	if err != nil {
-		p.errCh <- fmt.Errorf("Error unmarshalling XML from file %s: %v", filePath, err)
-		return nil, err
+		return nil, fmt.Errorf("error unmarshalling XML from file %s: %w", filePath, err)
 	}
 	mv["indexing"] = false
 	return mv, nil
@@ -68,20 +64,74 @@ func (p *Parser) BuildPatent(patentGrant *mongo.UsPatentGrant, storageID string)
 	}
 
 	var inventorNames []string
+	var inventors []mongo.Inventor
 	for _, inventor := range patentGrant.UsBibliographicDataGrant.UsParties.Inventors.Inventor {
 		inventorNames = append(inventorNames, inventor.Addressbook.FirstName.Text+" "+inventor.Addressbook.LastName.Text)
+		inventors = append(inventors, mongo.Inventor{
+			FirstName: inventor.Addressbook.FirstName.Text,
+			LastName:  inventor.Addressbook.LastName.Text,
+		})
 	}
 
 	patent := mongo.Patent{
 		PatentTitle:     patentGrant.UsBibliographicDataGrant.InventionTitle.Text,
 		PatentNumber:    patentGrant.UsBibliographicDataGrant.PublicationReference.DocumentID.DocNumber.Text,
 		InventorNames:   inventorNames,
+		Inventors:       inventors,
 		AssigneeName:    patentGrant.UsBibliographicDataGrant.Assignees.Assignee.Addressbook.Orgname.Text,
 		ApplicationDate: patentGrant.UsBibliographicDataGrant.ApplicationReference.DocumentID.Date.Text,
 		IssueDate:       patentGrant.UsBibliographicDataGrant.PublicationReference.DocumentID.Date.Text,
 		DesignClass:     patentGrant.UsBibliographicDataGrant.ClassificationsCpc.MainCpc.ClassificationCpc.Section.Text,
 		PatentStorageID: storageID,
+		Classifications: buildClassifications(patentGrant),
+		Citations:       buildCitations(patentGrant),
 	}
 
 	return &patent, nil
 }
+
+func buildClassifications(patentGrant *mongo.UsPatentGrant) []mongo.Classification {
+	cpc := patentGrant.UsBibliographicDataGrant.ClassificationsCpc
+	var classifications []mongo.Classification
+	if main := cpc.MainCpc.ClassificationCpc; main.Section.Text != "" {
+		classifications = append(classifications, mongo.Classification{
+			Section:   main.Section.Text,
+			Class:     main.Class.Text,
+			Subclass:  main.Subclass.Text,
+			MainGroup: main.MainGroup.Text,
+			Subgroup:  main.Subgroup.Text,
+			Main:      true,
+		})
+	}
+	for _, further := range cpc.FurtherCpc.ClassificationCpc {
+		classifications = append(classifications, mongo.Classification{
+			Section:   further.Section.Text,
+			Class:     further.Class.Text,
+			Subclass:  further.Subclass.Text,
+			MainGroup: further.MainGroup.Text,
+			Subgroup:  further.Subgroup.Text,
+		})
+	}
+	return classifications
+}
+
+// buildCitations returns the patent citations of a grant. Citations of other
+// literature are left out.
+func buildCitations(patentGrant *mongo.UsPatentGrant) []mongo.Citation {
+	var citations []mongo.Citation
+	for _, cited := range patentGrant.UsBibliographicDataGrant.UsReferencesCited.UsCitation {
+		document := cited.Patcit.DocumentID
+		if document.DocNumber.Text == "" {
+			continue
+		}
+		citations = append(citations, mongo.Citation{
+			Country:   document.Country.Text,
+			DocNumber: document.DocNumber.Text,
+			Kind:      document.Kind.Text,
+			Name:      document.Name.Text,
+			Date:      document.Date.Text,
+			Category:  cited.Category.Text,
+		})
+	}
+	return citations
+}
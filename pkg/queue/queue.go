@@ -2,7 +2,10 @@ package queue
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 )
@@ -13,92 +16,383 @@ type TaskType int
 const (
 	DownloadAndProcess TaskType = iota
 	WalkAndProcess
+	// UploadAndProcess ingests an uploaded .tar.gz, .zip or .xml file.
+	UploadAndProcess
 )
 
+func (t TaskType) String() string {
+	switch t {
+	case DownloadAndProcess:
+		return "download"
+	case WalkAndProcess:
+		return "crawl"
+	case UploadAndProcess:
+		return "upload"
+	}
+	return fmt.Sprintf("TaskType(%d)", int(t))
+}
+
 type Task struct {
+	// ID identifies the task while it is queued or running. It is set by
+	// Enqueue.
+	ID       uint64
 	FilePath string
 	Type     TaskType
+	// JobID is the ingestion job tracking the task, if any.
+	JobID string
 }
 
+// MaxWorkers bounds the number of workers SetWorkers accepts.
+const MaxWorkers = 64
+
+// States of a TaskInfo.
+const (
+	TaskQueued  = "queued"
+	TaskRunning = "running"
+)
+
+var (
+	// ErrFull is returned by Enqueue when every slot of the queue is taken.
+	ErrFull = errors.New("task queue is full, retry later")
+	// ErrStopped is returned by Enqueue once the queue is stopped.
+	ErrStopped = errors.New("task queue is stopped")
+	// ErrDraining is returned by Enqueue while the queue is drained, until it
+	// is resumed.
+	ErrDraining = errors.New("task queue is draining, retry later")
+	// ErrTaskNotFound is returned by Cancel for a task that is neither queued
+	// nor running.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrCancelled is the error of the jobs of cancelled tasks.
+	ErrCancelled = errors.New("task cancelled by an operator")
+)
+
 // TaskProcessor is an interface that represents the ability to process tasks.
 type TaskProcessor interface {
 	Process(ctx context.Context, task Task) error
 }
 
-// TaskQueue manages a queue of tasks and processes them using the provided TaskProcessor.
+// TaskInfo describes a queued or running task. Source is the link, directory
+// or upload the task ingests.
+type TaskInfo struct {
+	ID         uint64     `json:"id"`
+	Type       string     `json:"type"`
+	Source     string     `json:"source"`
+	JobID      string     `json:"jobId,omitempty"`
+	State      string     `json:"state"`
+	EnqueuedAt time.Time  `json:"enqueuedAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+}
+
+// Stats is a snapshot of the state of a TaskQueue.
+type Stats struct {
+	// Workers is the number of running workers, and TargetWorkers the number
+	// wanted. Workers exit when the queue is stopped or the context given to
+	// Start is cancelled, and after their task when there are too many.
+	Workers       int  `json:"workers"`
+	TargetWorkers int  `json:"targetWorkers"`
+	Depth         int  `json:"depth"`
+	Running       int  `json:"running"`
+	Capacity      int  `json:"capacity"`
+	Paused        bool `json:"paused"`
+	Draining      bool `json:"draining"`
+	Stopped       bool `json:"stopped"`
+	// TaskTimeoutSeconds is how long a task may run before it is cancelled,
+	// 0 when tasks run until they finish.
+	TaskTimeoutSeconds int `json:"taskTimeoutSeconds"`
+}
+
+// TaskQueue manages a queue of tasks and processes them using the provided
+// TaskProcessor. Tasks are run in the order they were queued.
 type TaskQueue struct {
-	tasks     chan Task
-	wg        *sync.WaitGroup
-	processor TaskProcessor
-	resume    chan struct{}
+	processor   TaskProcessor
+	capacity    int
+	taskTimeout time.Duration
+	wg          sync.WaitGroup
+
+	// mu guards the fields below. cond is broadcast whenever a task is
+	// queued or the state of the queue changes, to wake idle workers.
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ctx     context.Context
+	pending []*taskEntry
+	running map[uint64]*taskEntry
+	nextID  uint64
+	// workers is the number of worker goroutines, and target the number
+	// wanted.
+	workers  int
+	target   int
+	paused   bool
+	draining bool
+	stopped  bool
+}
+
+// taskEntry is a queued or running task. cancel is only set once it runs.
+type taskEntry struct {
+	task       Task
+	enqueuedAt time.Time
+	startedAt  time.Time
+	cancel     context.CancelFunc
 }
 
-// NewTaskQueue creates a new TaskQueue with the given TaskProcessor and size.
-func NewTaskQueue(size int, processor TaskProcessor) *TaskQueue {
+func (e *taskEntry) info() TaskInfo {
+	info := TaskInfo{
+		ID:         e.task.ID,
+		Type:       e.task.Type.String(),
+		Source:     e.task.FilePath,
+		JobID:      e.task.JobID,
+		State:      TaskQueued,
+		EnqueuedAt: e.enqueuedAt,
+	}
+	if e.cancel != nil {
+		startedAt := e.startedAt
+		info.State = TaskRunning
+		info.StartedAt = &startedAt
+	}
+	return info
+}
+
+// NewTaskQueue creates a new TaskQueue with the given TaskProcessor, holding
+// up to size tasks and processing them with size workers. A task running
+// longer than taskTimeout is cancelled; zero lets tasks run until they finish.
+func NewTaskQueue(size int, taskTimeout time.Duration, processor TaskProcessor) *TaskQueue {
 	log.Println("Initializing TaskQueue with size:", size)
 	q := &TaskQueue{
-		tasks:     make(chan Task, size),
-		wg:        &sync.WaitGroup{},
-		processor: processor,
-		resume:    make(chan struct{}),
+		processor:   processor,
+		capacity:    size,
+		taskTimeout: taskTimeout,
+		running:     make(map[uint64]*taskEntry),
+		target:      size,
 	}
+	q.cond = sync.NewCond(&q.mu)
 	log.Println("TaskQueue Initialized.")
 	return q
 }
 
-// Enqueue adds a new task to the queue and resumes a worker.
-func (q *TaskQueue) Enqueue(task Task) {
-	log.Printf("Enqueueing task: %+v\n", task)
-	q.tasks <- task
+// Enqueue adds a new task to the queue and wakes an idle worker. It does not
+// wait for a free slot, and returns ErrFull when there is none.
+func (q *TaskQueue) Enqueue(task Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case q.stopped:
+		return ErrStopped
+	case q.draining:
+		return ErrDraining
+	case len(q.pending) >= q.capacity:
+		return ErrFull
+	}
+
+	q.nextID++
+	task.ID = q.nextID
+	q.pending = append(q.pending, &taskEntry{task: task, enqueuedAt: time.Now()})
 	log.Printf("Task: %+v enqueued.\n", task)
-	log.Println("Sending signal to resume a worker.")
-	q.resume <- struct{}{}
-	log.Println("Signal sent to resume a worker.")
+	q.cond.Signal()
+	return nil
 }
 
-// Start initializes workers to process tasks.
+// Start initializes workers to process tasks. They exit when ctx is
+// cancelled, abandoning the queued tasks.
 func (q *TaskQueue) Start(ctx context.Context) {
 	log.Println("Starting workers")
-	for i := 0; i < cap(q.tasks); i++ {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ctx = ctx
+	q.spawn()
+
+	go func() {
+		<-ctx.Done()
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.cond.Broadcast()
+	}()
+}
+
+// spawn starts workers until there are as many as wanted. q.mu must be held.
+func (q *TaskQueue) spawn() {
+	for q.workers < q.target {
+		q.workers++
 		q.wg.Add(1)
-		go q.worker(ctx)
-		log.Printf("Worker %d started.\n", i)
+		go q.worker()
+		log.Printf("Worker %d started.\n", q.workers)
 	}
 }
 
-// Stop waits for all workers to finish processing and closes the tasks channel.
+// Stop stops accepting tasks and waits for the workers to finish the queued
+// ones, unless the queue is paused.
 func (q *TaskQueue) Stop() {
-	log.Println("Stopping TaskQueue, closing tasks channel.")
-	close(q.tasks)
+	log.Println("Stopping TaskQueue.")
+	q.mu.Lock()
+	q.stopped = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
 	q.wg.Wait()
 	log.Println("All workers have finished processing, TaskQueue stopped.")
 }
 
+// Tasks lists the running tasks, oldest first, followed by the queued ones in
+// the order they will run.
+func (q *TaskQueue) Tasks() []TaskInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tasks := make([]TaskInfo, 0, len(q.running)+len(q.pending))
+	for _, entry := range q.running {
+		tasks = append(tasks, entry.info())
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	for _, entry := range q.pending {
+		tasks = append(tasks, entry.info())
+	}
+	return tasks
+}
+
+// Cancel removes a queued task, or cancels the context of a running one, and
+// returns it as it was. A running task ends once its processor returns.
+func (q *TaskQueue) Cancel(id uint64) (TaskInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if entry, ok := q.running[id]; ok {
+		entry.cancel()
+		log.Printf("Running task %d cancelled.\n", id)
+		return entry.info(), nil
+	}
+	for i, entry := range q.pending {
+		if entry.task.ID == id {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			log.Printf("Queued task %d cancelled.\n", id)
+			return entry.info(), nil
+		}
+	}
+	return TaskInfo{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+}
+
+// Pause stops the workers from taking queued tasks. Running tasks go on, and
+// tasks are still accepted.
+func (q *TaskQueue) Pause() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.paused = true
+	log.Println("TaskQueue paused.")
+}
+
+// Drain stops accepting tasks, with ErrDraining, while the workers finish the
+// queued ones.
+func (q *TaskQueue) Drain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.draining = true
+	log.Println("TaskQueue draining.")
+}
+
+// Resume ends a pause or a drain.
+func (q *TaskQueue) Resume() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.paused = false
+	q.draining = false
+	q.cond.Broadcast()
+	log.Println("TaskQueue resumed.")
+}
+
+// SetWorkers changes the number of workers. Extra workers exit once their
+// task is done.
+func (q *TaskQueue) SetWorkers(n int) error {
+	if n < 1 || n > MaxWorkers {
+		return fmt.Errorf("workers must be between 1 and %d", MaxWorkers)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped {
+		return ErrStopped
+	}
+	log.Printf("Changing TaskQueue workers from %d to %d.\n", q.target, n)
+	q.target = n
+	if q.ctx != nil {
+		q.spawn()
+	}
+	q.cond.Broadcast()
+	return nil
+}
+
+// Stats returns the worker count and depth of the queue.
+func (q *TaskQueue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return Stats{
+		Workers:            q.workers,
+		TargetWorkers:      q.target,
+		Depth:              len(q.pending),
+		Running:            len(q.running),
+		Capacity:           q.capacity,
+		Paused:             q.paused,
+		Draining:           q.draining,
+		Stopped:            q.stopped,
+		TaskTimeoutSeconds: int(q.taskTimeout / time.Second),
+	}
+}
+
+// taskContext returns the context of a task, cancelled by Cancel and after
+// taskTimeout when it is set.
+func (q *TaskQueue) taskContext() (context.Context, context.CancelFunc) {
+	if q.taskTimeout > 0 {
+		return context.WithTimeout(q.ctx, q.taskTimeout)
+	}
+	return context.WithCancel(q.ctx)
+}
+
 // worker is a goroutine that processes tasks from the queue.
-func (q *TaskQueue) worker(ctx context.Context) {
+func (q *TaskQueue) worker() {
 	log.Println("Worker goroutine is running.")
 	defer q.wg.Done()
 	for {
-		select {
-		case task, ok := <-q.tasks:
-			if !ok {
-				log.Println("Tasks channel closed, exiting worker.")
-				return
-			}
-			log.Printf("Processing task: %+v\n", task)
-			taskCtx, cancel := context.WithTimeout(ctx, 100*time.Second)
-			if err := q.processor.Process(taskCtx, task); err != nil {
-				log.Printf("Error processing task %+v: %v\n", task, err)
-			} else {
-				log.Printf("Task %+v processed successfully.\n", task)
-			}
-			cancel()
-		case <-q.resume:
-			log.Println("Worker resumed.")
-			continue
-		case <-ctx.Done():
-			log.Println("Context done, exiting worker.")
+		entry, ctx := q.next()
+		if entry == nil {
 			return
 		}
+
+		log.Printf("Processing task: %+v\n", entry.task)
+		if err := q.processor.Process(ctx, entry.task); err != nil {
+			log.Printf("Error processing task %+v: %v\n", entry.task, err)
+		} else {
+			log.Printf("Task %+v processed successfully.\n", entry.task)
+		}
+		entry.cancel()
+
+		q.mu.Lock()
+		delete(q.running, entry.task.ID)
+		q.mu.Unlock()
+	}
+}
+
+// next waits for a task to run and marks it running. It returns nil when the
+// worker must exit: the context is cancelled, there are more workers than
+// wanted, or the queue is stopped and has nothing left to run.
+func (q *TaskQueue) next() (*taskEntry, context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		switch {
+		case q.ctx.Err() != nil:
+			log.Println("Context done, exiting worker.")
+			q.workers--
+			return nil, nil
+		case q.workers > q.target:
+			log.Println("Too many workers, exiting worker.")
+			q.workers--
+			return nil, nil
+		case len(q.pending) > 0 && !q.paused:
+			entry := q.pending[0]
+			q.pending[0] = nil
+			q.pending = q.pending[1:]
+			ctx, cancel := q.taskContext()
+			entry.startedAt = time.Now()
+			entry.cancel = cancel
+			q.running[entry.task.ID] = entry
+			return entry, ctx
+		case q.stopped:
+			log.Println("TaskQueue stopped, exiting worker.")
+			q.workers--
+			return nil, nil
+		}
+		q.cond.Wait()
 	}
 }
@@ -0,0 +1,250 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProcessor records the tasks it processes. Each task blocks until it is
+// released or its context is done.
+type fakeProcessor struct {
+	mu        sync.Mutex
+	processed []uint64
+	cancelled []uint64
+	release   chan struct{}
+}
+
+func newFakeProcessor() *fakeProcessor {
+	return &fakeProcessor{release: make(chan struct{})}
+}
+
+func (p *fakeProcessor) Process(ctx context.Context, task Task) error {
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		p.mu.Lock()
+		p.cancelled = append(p.cancelled, task.ID)
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+	p.mu.Lock()
+	p.processed = append(p.processed, task.ID)
+	p.mu.Unlock()
+	return nil
+}
+
+// releaseAll lets every current and future task finish.
+func (p *fakeProcessor) releaseAll() {
+	close(p.release)
+}
+
+func (p *fakeProcessor) results() (processed, cancelled []uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]uint64(nil), p.processed...), append([]uint64(nil), p.cancelled...)
+}
+
+// waitFor fails the test when cond does not hold within a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// startQueue starts a queue of 4 slots processed by the given number of
+// workers.
+func startQueue(t *testing.T, workers int, p *fakeProcessor) *TaskQueue {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	q := NewTaskQueue(4, 0, p)
+	if err := q.SetWorkers(workers); err != nil {
+		t.Fatalf("SetWorkers: %v", err)
+	}
+	q.Start(ctx)
+	waitFor(t, "workers", func() bool { return q.Stats().Workers == workers })
+	return q
+}
+
+func enqueue(t *testing.T, q *TaskQueue, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := q.Enqueue(Task{FilePath: "task", JobID: "job"}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	p := newFakeProcessor()
+	q := startQueue(t, 1, p)
+	enqueue(t, q, 2)
+	waitFor(t, "task 1 to run", func() bool { return q.Stats().Running == 1 })
+
+	tests := []struct {
+		name      string
+		id        uint64
+		wantState string
+		wantErr   error
+	}{
+		{"queued", 2, TaskQueued, nil},
+		{"running", 1, TaskRunning, nil},
+		{"unknown", 3, "", ErrTaskNotFound},
+		{"already cancelled", 2, "", ErrTaskNotFound},
+	}
+	for _, tt := range tests {
+		task, err := q.Cancel(tt.id)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Cancel(%d) error = %v, want %v", tt.name, tt.id, err, tt.wantErr)
+		}
+		if task.State != tt.wantState {
+			t.Errorf("%s: Cancel(%d) state = %q, want %q", tt.name, tt.id, task.State, tt.wantState)
+		}
+	}
+
+	waitFor(t, "the running task to stop", func() bool { return q.Stats().Running == 0 })
+	if processed, cancelled := p.results(); len(processed) != 0 || len(cancelled) != 1 || cancelled[0] != 1 {
+		t.Errorf("processed %v and cancelled %v, want only task 1 cancelled", processed, cancelled)
+	}
+	if tasks := q.Tasks(); len(tasks) != 0 {
+		t.Errorf("Tasks() = %+v, want none", tasks)
+	}
+}
+
+func TestPause(t *testing.T) {
+	p := newFakeProcessor()
+	p.releaseAll()
+	q := startQueue(t, 2, p)
+
+	q.Pause()
+	enqueue(t, q, 2)
+	time.Sleep(20 * time.Millisecond)
+	if stats := q.Stats(); stats.Depth != 2 || stats.Running != 0 || !stats.Paused {
+		t.Fatalf("paused queue stats = %+v, want 2 tasks held", stats)
+	}
+
+	q.Resume()
+	waitFor(t, "tasks to run", func() bool {
+		processed, _ := p.results()
+		return len(processed) == 2
+	})
+}
+
+func TestDrain(t *testing.T) {
+	p := newFakeProcessor()
+	q := startQueue(t, 1, p)
+	enqueue(t, q, 2)
+
+	q.Drain()
+	if err := q.Enqueue(Task{}); !errors.Is(err, ErrDraining) {
+		t.Fatalf("Enqueue while draining = %v, want ErrDraining", err)
+	}
+
+	// Queued tasks still run while draining.
+	p.releaseAll()
+	waitFor(t, "queued tasks to run", func() bool {
+		processed, _ := p.results()
+		return len(processed) == 2
+	})
+
+	q.Resume()
+	if err := q.Enqueue(Task{}); err != nil {
+		t.Fatalf("Enqueue after resume = %v", err)
+	}
+}
+
+func TestEnqueueFull(t *testing.T) {
+	q := NewTaskQueue(2, 0, newFakeProcessor())
+	enqueue(t, q, 2)
+	if err := q.Enqueue(Task{}); !errors.Is(err, ErrFull) {
+		t.Fatalf("Enqueue on a full queue = %v, want ErrFull", err)
+	}
+}
+
+func TestSetWorkers(t *testing.T) {
+	p := newFakeProcessor()
+	q := startQueue(t, 2, p)
+
+	if err := q.SetWorkers(5); err != nil {
+		t.Fatalf("SetWorkers(5): %v", err)
+	}
+	waitFor(t, "5 workers", func() bool { return q.Stats().Workers == 5 })
+
+	// Shrinking waits for running tasks to finish.
+	enqueue(t, q, 3)
+	waitFor(t, "3 running tasks", func() bool { return q.Stats().Running == 3 })
+	if err := q.SetWorkers(1); err != nil {
+		t.Fatalf("SetWorkers(1): %v", err)
+	}
+	waitFor(t, "idle workers to exit", func() bool { return q.Stats().Workers == 3 })
+	if stats := q.Stats(); stats.TargetWorkers != 1 || stats.Running != 3 {
+		t.Fatalf("stats = %+v, want 3 tasks still running", stats)
+	}
+	p.releaseAll()
+	waitFor(t, "1 worker", func() bool { return q.Stats().Workers == 1 })
+
+	for _, n := range []int{0, MaxWorkers + 1} {
+		if err := q.SetWorkers(n); err == nil {
+			t.Errorf("SetWorkers(%d) succeeded, want an error", n)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	p := newFakeProcessor()
+	q := startQueue(t, 1, p)
+	enqueue(t, q, 3)
+
+	stopped := make(chan struct{})
+	go func() {
+		q.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before the queued tasks ran")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := q.Enqueue(Task{}); !errors.Is(err, ErrStopped) {
+		t.Errorf("Enqueue after Stop = %v, want ErrStopped", err)
+	}
+
+	p.releaseAll()
+	<-stopped
+	if processed, _ := p.results(); len(processed) != 3 {
+		t.Errorf("processed %v, want the 3 queued tasks", processed)
+	}
+	if stats := q.Stats(); stats.Workers != 0 || !stats.Stopped {
+		t.Errorf("stats after Stop = %+v, want no worker", stats)
+	}
+}
+
+func TestTaskTimeout(t *testing.T) {
+	p := newFakeProcessor()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := NewTaskQueue(1, 10*time.Millisecond, p)
+	q.Start(ctx)
+	if got := q.Stats().TaskTimeoutSeconds; got != 0 {
+		t.Fatalf("TaskTimeoutSeconds = %d, want 0 for a sub-second timeout", got)
+	}
+
+	enqueue(t, q, 1)
+	waitFor(t, "the task to time out", func() bool {
+		_, cancelled := p.results()
+		return len(cancelled) == 1
+	})
+	waitFor(t, "the task to stop", func() bool { return q.Stats().Running == 0 })
+
+	if got := NewTaskQueue(1, 90*time.Second, p).Stats().TaskTimeoutSeconds; got != 90 {
+		t.Fatalf("TaskTimeoutSeconds = %d, want 90", got)
+	}
+}
@@ -0,0 +1,251 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/auth"
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Route groups, each limited separately.
+const (
+	GroupSearch    = "search"
+	GroupIngestion = "ingestion"
+)
+
+// Groups lists the route groups.
+var Groups = []string{GroupSearch, GroupIngestion}
+
+// sweepThreshold is the number of token buckets above which buckets that
+// have refilled completely are forgotten.
+const sweepThreshold = 10000
+
+// Limits holds the limiter of every route group.
+type Limits struct {
+	Search    *Limiter
+	Ingestion *Limiter
+}
+
+func NewLimits(db *mongo.Database, cfg *config.RateLimitConfig) *Limits {
+	return &Limits{
+		Search:    NewLimiter(db, GroupSearch, cfg.Search),
+		Ingestion: NewLimiter(db, GroupIngestion, cfg.Ingestion),
+	}
+}
+
+// Limiter limits the requests of each caller to a route group with a token
+// bucket, kept in memory, and a daily quota, counted in MongoDB. Callers are
+// told by API key, or by client IP when authentication is disabled.
+type Limiter struct {
+	db     *mongo.Database
+	group  string
+	policy config.RateLimitPolicy
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewLimiter(db *mongo.Database, group string, policy config.RateLimitPolicy) *Limiter {
+	return &Limiter{
+		db:      db,
+		group:   group,
+		policy:  policy,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// window is the state of one limit for a caller, as reported in the
+// RateLimit headers.
+type window struct {
+	policy    string
+	limit     int64
+	remaining int64
+	reset     time.Duration
+}
+
+// Decision is the outcome of checking a request against the limits of a
+// group. When the request is rejected, Reason tells which limit was reached
+// and RetryAfter when to retry.
+type Decision struct {
+	Allowed    bool
+	Reason     string
+	RetryAfter time.Duration
+	windows    []window
+}
+
+// Allow takes a request of the caller holding apiKey, or of the client ip
+// when apiKey is nil, from its token bucket and daily quota. Quota counting
+// failures are logged and let the request through.
+func (l *Limiter) Allow(apiKey *mongo.APIKey, ip string) Decision {
+	caller, quota := l.caller(apiKey, ip)
+	now := time.Now()
+
+	var d Decision
+	if l.bucketEnabled() {
+		allowed, w, retryAfter := l.take(caller, now)
+		d.windows = append(d.windows, w)
+		if !allowed {
+			d.Reason, d.RetryAfter = "rate limit exceeded", retryAfter
+			return d
+		}
+	}
+
+	if quota > 0 {
+		usage, err := l.db.IncrementQuotaUsage(caller, l.group, now)
+		if err != nil {
+			log.Printf("Error counting %s quota usage: %v", l.group, err)
+		} else {
+			w := window{
+				policy:    fmt.Sprintf("%d;w=86400", quota),
+				limit:     quota,
+				remaining: max(quota-usage.Count, 0),
+				reset:     untilMidnight(now),
+			}
+			d.windows = append(d.windows, w)
+			if usage.Count > quota {
+				d.Reason, d.RetryAfter = "daily quota exceeded", w.reset
+				return d
+			}
+		}
+	}
+
+	d.Allowed = true
+	return d
+}
+
+// Handler rejects requests over the token bucket or the daily quota with 429
+// and a Retry-After header. Every response of the group carries RateLimit
+// headers describing the limit closest to being reached.
+func (l *Limiter) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		d := l.Allow(auth.KeyFrom(c), c.IP())
+		setHeaders(c, d.windows)
+		if !d.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(d.RetryAfterSeconds()))
+			return problem.New(fiber.StatusTooManyRequests, d.Reason)
+		}
+		return c.Next()
+	}
+}
+
+// RetryAfterSeconds returns RetryAfter rounded up to whole seconds, at least
+// one, as sent in Retry-After.
+func (d Decision) RetryAfterSeconds() int {
+	return max(ceilSeconds(d.RetryAfter), 1)
+}
+
+// caller identifies the caller of a request and returns its daily quota.
+func (l *Limiter) caller(apiKey *mongo.APIKey, ip string) (string, int64) {
+	if apiKey == nil {
+		return "ip:" + ip, l.policy.DailyQuota
+	}
+	if quota, ok := apiKey.DailyQuotas[l.group]; ok {
+		return apiKey.ID.Hex(), quota
+	}
+	return apiKey.ID.Hex(), l.policy.DailyQuota
+}
+
+func (l *Limiter) bucketEnabled() bool {
+	return l.policy.PerMinute > 0 && l.policy.Burst > 0
+}
+
+// rate is the number of tokens added to a bucket per second.
+func (l *Limiter) rate() float64 {
+	return float64(l.policy.PerMinute) / 60
+}
+
+// take removes a token from the bucket of caller. When the bucket is empty,
+// it returns how long until the next token.
+func (l *Limiter) take(caller string, now time.Time) (bool, window, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	burst := float64(l.policy.Burst)
+	b, ok := l.buckets[caller]
+	if !ok {
+		if len(l.buckets) >= sweepThreshold {
+			l.sweep(now)
+		}
+		b = &bucket{tokens: burst, updated: now}
+		l.buckets[caller] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate())
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	w := window{
+		policy:    fmt.Sprintf("%d;w=%d", l.policy.Burst, int(math.Ceil(burst/l.rate()))),
+		limit:     int64(l.policy.Burst),
+		remaining: int64(b.tokens),
+		reset:     l.seconds(burst - b.tokens),
+	}
+	return allowed, w, l.seconds(1 - b.tokens)
+}
+
+// seconds returns how long the bucket takes to gain tokens.
+func (l *Limiter) seconds(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.rate() * float64(time.Second))
+}
+
+// sweep forgets the buckets that have refilled completely, which behave as
+// new ones. l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	burst := float64(l.policy.Burst)
+	for caller, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate() >= burst {
+			delete(l.buckets, caller)
+		}
+	}
+}
+
+// setHeaders sets the RateLimit-Policy header listing every limit of the
+// group, and RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset for the
+// limit with the fewest requests remaining.
+func setHeaders(c *fiber.Ctx, windows []window) {
+	if len(windows) == 0 {
+		return
+	}
+	policies := make([]string, 0, len(windows))
+	closest := windows[0]
+	for _, w := range windows {
+		policies = append(policies, w.policy)
+		if w.remaining < closest.remaining {
+			closest = w
+		}
+	}
+	c.Set("RateLimit-Policy", strings.Join(policies, ", "))
+	c.Set("RateLimit-Limit", strconv.FormatInt(closest.limit, 10))
+	c.Set("RateLimit-Remaining", strconv.FormatInt(closest.remaining, 10))
+	c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(closest.reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// untilMidnight returns the time left in the UTC day of now, when daily
+// quotas reset.
+func untilMidnight(now time.Time) time.Duration {
+	now = now.UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/api/problem"
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTake(t *testing.T) {
+	start := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	type step struct {
+		// after is the time since start of the request.
+		after          time.Duration
+		wantAllowed    bool
+		wantRemaining  int64
+		wantRetryAfter time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "burst then exhausted",
+			steps: []step{
+				{0, true, 2, 0},
+				{0, true, 1, 0},
+				{0, true, 0, 0},
+				{0, false, 0, 2 * time.Second},
+			},
+		},
+		{
+			name: "partial refill",
+			steps: []step{
+				{0, true, 2, 0},
+				{0, true, 1, 0},
+				{0, true, 0, 0},
+				{time.Second, false, 0, time.Second},
+				{2 * time.Second, true, 0, 0},
+				{2 * time.Second, false, 0, 2 * time.Second},
+			},
+		},
+		{
+			name: "refill capped at burst",
+			steps: []step{
+				{0, true, 2, 0},
+				{time.Hour, true, 2, 0},
+				{time.Hour, true, 1, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 30 tokens per minute: one every 2 seconds.
+			l := NewLimiter(nil, GroupSearch, config.RateLimitPolicy{PerMinute: 30, Burst: 3})
+			for i, s := range tt.steps {
+				allowed, w, retryAfter := l.take("caller", start.Add(s.after))
+				if allowed != s.wantAllowed || w.remaining != s.wantRemaining {
+					t.Fatalf("request %d: allowed = %v with %d remaining, want %v with %d", i, allowed, w.remaining, s.wantAllowed, s.wantRemaining)
+				}
+				if !allowed && retryAfter != s.wantRetryAfter {
+					t.Fatalf("request %d: retry after %v, want %v", i, retryAfter, s.wantRetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestTakeSeparateCallers(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(nil, GroupSearch, config.RateLimitPolicy{PerMinute: 60, Burst: 1})
+	if allowed, _, _ := l.take("a", now); !allowed {
+		t.Fatal("first request of a rejected")
+	}
+	if allowed, _, _ := l.take("a", now); allowed {
+		t.Fatal("second request of a allowed")
+	}
+	if allowed, _, _ := l.take("b", now); !allowed {
+		t.Fatal("first request of b rejected")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(nil, GroupSearch, config.RateLimitPolicy{PerMinute: 60, Burst: 2})
+	l.take("refilled", now.Add(-time.Minute))
+	l.take("drained", now)
+	l.sweep(now)
+	if _, ok := l.buckets["refilled"]; ok {
+		t.Error("refilled bucket kept")
+	}
+	if _, ok := l.buckets["drained"]; !ok {
+		t.Error("drained bucket forgotten")
+	}
+}
+
+func TestCaller(t *testing.T) {
+	apiKey := &mongo.APIKey{ID: primitive.NewObjectID(), DailyQuotas: map[string]int64{GroupIngestion: 5}}
+	l := NewLimiter(nil, GroupIngestion, config.RateLimitPolicy{DailyQuota: 100})
+
+	if caller, quota := l.caller(nil, "10.0.0.1"); caller != "ip:10.0.0.1" || quota != 100 {
+		t.Errorf("caller(nil) = %s, %d", caller, quota)
+	}
+	if caller, quota := l.caller(apiKey, "10.0.0.1"); caller != apiKey.ID.Hex() || quota != 5 {
+		t.Errorf("caller(apiKey) = %s, %d, want the key's own quota", caller, quota)
+	}
+	search := NewLimiter(nil, GroupSearch, config.RateLimitPolicy{DailyQuota: 100})
+	if _, quota := search.caller(apiKey, "10.0.0.1"); quota != 100 {
+		t.Errorf("caller(apiKey) quota = %d, want the group's", quota)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Use(NewLimiter(nil, GroupSearch, config.RateLimitPolicy{PerMinute: 1, Burst: 2}).Handler())
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	tests := []struct {
+		wantStatus     int
+		wantRemaining  string
+		wantRetryAfter string
+	}{
+		{fiber.StatusOK, "1", ""},
+		{fiber.StatusOK, "0", ""},
+		{fiber.StatusTooManyRequests, "0", "60"},
+	}
+	for i, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.wantStatus {
+			t.Fatalf("request %d: status = %d, want %d", i, resp.StatusCode, tt.wantStatus)
+		}
+		if got := resp.Header.Get("RateLimit-Policy"); got != "2;w=120" {
+			t.Errorf("request %d: RateLimit-Policy = %q", i, got)
+		}
+		if got := resp.Header.Get("RateLimit-Remaining"); got != tt.wantRemaining {
+			t.Errorf("request %d: RateLimit-Remaining = %q, want %q", i, got, tt.wantRemaining)
+		}
+		if got := resp.Header.Get(fiber.HeaderRetryAfter); got != tt.wantRetryAfter {
+			t.Errorf("request %d: Retry-After = %q, want %q", i, got, tt.wantRetryAfter)
+		}
+	}
+}
+
+func TestUntilMidnight(t *testing.T) {
+	now := time.Date(2023, 6, 1, 23, 59, 30, 0, time.FixedZone("CEST", 2*60*60))
+	if got := untilMidnight(now); got != 2*time.Hour+30*time.Second {
+		t.Errorf("untilMidnight() = %v", got)
+	}
+}
@@ -0,0 +1,310 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/indexer"
+)
+
+const (
+	manifestFile = "manifest.json"
+	indexPrefix  = "index/"
+	extension    = ".tar.gz"
+	nameLayout   = "20060102T150405Z"
+)
+
+var (
+	// ErrNotFound is returned when a snapshot does not exist.
+	ErrNotFound = errors.New("snapshot not found")
+	// ErrInvalidSnapshot is returned for snapshot names or archives that cannot be restored.
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+	// ErrMappingVersion is returned when a snapshot was taken with a different index mapping.
+	ErrMappingVersion = errors.New("snapshot mapping version does not match the index")
+)
+
+// Manifest describes a snapshot. It is the first entry of every archive.
+type Manifest struct {
+	Name           string    `json:"name"`
+	CreatedAt      time.Time `json:"createdAt"`
+	MappingVersion int       `json:"mappingVersion"`
+	Size           int64     `json:"size,omitempty"`
+}
+
+// Manager writes index snapshots as tarballs into a directory and restores
+// them. Only one snapshot is created or restored at a time.
+type Manager struct {
+	mu      sync.Mutex
+	dir     string
+	backend indexer.SearchBackend
+}
+
+func NewManager(dir string, backend indexer.SearchBackend) *Manager {
+	return &Manager{
+		dir:     dir,
+		backend: backend,
+	}
+}
+
+// Create snapshots the index into a new tarball and returns its manifest.
+func (m *Manager) Create() (*Manifest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating snapshot directory: %v", err)
+	}
+	staging, err := os.MkdirTemp(m.dir, ".snapshot-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating staging directory: %v", err)
+	}
+	defer os.RemoveAll(staging)
+
+	indexDir := filepath.Join(staging, "index")
+	if err := m.backend.Snapshot(indexDir); err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC()
+	manifest := &Manifest{
+		Name:           "snapshot-" + createdAt.Format(nameLayout),
+		CreatedAt:      createdAt,
+		MappingVersion: indexer.MappingVersion,
+	}
+	path := filepath.Join(m.dir, manifest.Name+extension)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("snapshot %s already exists", manifest.Name)
+	}
+
+	// Write to a temporary name so that List never sees a partial archive.
+	partial := filepath.Join(staging, manifest.Name+extension)
+	if err := writeArchive(partial, manifest, indexDir); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return nil, fmt.Errorf("error saving snapshot: %v", err)
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		manifest.Size = info.Size()
+	}
+	return manifest, nil
+}
+
+// List returns the manifests of the snapshots in the directory, newest first.
+func (m *Manager) List() ([]Manifest, error) {
+	entries, err := os.ReadDir(m.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot directory: %v", err)
+	}
+
+	manifests := []Manifest{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), extension) {
+			continue
+		}
+		manifest, err := readManifest(filepath.Join(m.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", entry.Name(), err)
+		}
+		if info, err := entry.Info(); err == nil {
+			manifest.Size = info.Size()
+		}
+		manifests = append(manifests, *manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].CreatedAt.After(manifests[j].CreatedAt) })
+	return manifests, nil
+}
+
+// Restore replaces the index with the named snapshot.
+func (m *Manager) Restore(name string) (*Manifest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = strings.TrimSuffix(name, extension)
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("snapshot name %q: %w", name, ErrInvalidSnapshot)
+	}
+	path := filepath.Join(m.dir, name+extension)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("snapshot %s: %w", name, ErrNotFound)
+	}
+
+	manifest, err := readManifest(path)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", name, err)
+	}
+	if manifest.MappingVersion != indexer.MappingVersion {
+		return nil, fmt.Errorf("snapshot %s has mapping version %d, index uses %d: %w",
+			name, manifest.MappingVersion, indexer.MappingVersion, ErrMappingVersion)
+	}
+
+	staging, err := os.MkdirTemp(m.dir, ".restore-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating staging directory: %v", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := extractIndex(path, staging); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", name, err)
+	}
+	if err := m.backend.Restore(filepath.Join(staging, "index")); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// writeArchive writes the manifest followed by the files of indexDir into a
+// gzipped tarball at path.
+func writeArchive(path string, manifest *Manifest, indexDir string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot archive: %v", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling manifest: %v", err)
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    manifestFile,
+		Mode:    0o644,
+		Size:    int64(len(manifestBytes)),
+		ModTime: manifest.CreatedAt,
+	}); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+	if _, err := tw.Write(manifestBytes); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+
+	err = filepath.Walk(indexDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(indexDir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = indexPrefix + filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error archiving index: %v", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error writing snapshot archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("error writing snapshot archive: %v", err)
+	}
+	return file.Sync()
+}
+
+func openArchive(path string) (*tar.Reader, func() error, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%v: %w", err, ErrInvalidSnapshot)
+	}
+	closer := func() error {
+		gz.Close()
+		return file.Close()
+	}
+	return tar.NewReader(gz), closer, nil
+}
+
+func readManifest(path string) (*Manifest, error) {
+	tr, closer, err := openArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closer()
+
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestFile {
+		return nil, fmt.Errorf("missing manifest: %w", ErrInvalidSnapshot)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("error decoding manifest: %v: %w", err, ErrInvalidSnapshot)
+	}
+	return &manifest, nil
+}
+
+// extractIndex unpacks the index files of the archive at path into dir/index.
+func extractIndex(path, dir string) error {
+	tr, closer, err := openArchive(path)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive: %v: %w", err, ErrInvalidSnapshot)
+		}
+		if header.Typeflag != tar.TypeReg || !strings.HasPrefix(header.Name, indexPrefix) {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("entry %q escapes the archive: %w", header.Name, ErrInvalidSnapshot)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+}
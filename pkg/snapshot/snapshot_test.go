@@ -0,0 +1,144 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/config"
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/indexer"
+)
+
+func newEngine(t *testing.T, indexDir string) *indexer.SearchEngine {
+	t.Helper()
+	indexMapping, err := indexer.NewIndexMapping(&config.AnalyzerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var engine *indexer.SearchEngine
+	if indexDir == "" {
+		engine, err = indexer.NewMemSearchEngine(indexMapping)
+	} else {
+		engine, err = indexer.NewSearchEngine(indexDir, indexMapping)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+func indexed(t *testing.T, engine *indexer.SearchEngine, patentID string) bool {
+	t.Helper()
+	_, err := engine.LookupPatent(patentID)
+	if errors.Is(err, indexer.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return true
+}
+
+func TestCreateRestore(t *testing.T) {
+	dir := t.TempDir()
+	engine := newEngine(t, filepath.Join(dir, "index"))
+	m := NewManager(filepath.Join(dir, "snapshots"), engine)
+
+	if err := engine.IndexPatent(&mongo.Patent{PatentStorageID: "before", PatentTitle: "Office chair"}); err != nil {
+		t.Fatal(err)
+	}
+	created, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.MappingVersion != indexer.MappingVersion || created.Size == 0 {
+		t.Fatalf("Create() = %+v", created)
+	}
+	if err := engine.IndexPatent(&mongo.Patent{PatentStorageID: "after", PatentTitle: "Folding chair"}); err != nil {
+		t.Fatal(err)
+	}
+
+	manifests, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != 1 || manifests[0].Name != created.Name {
+		t.Fatalf("List() = %+v, want the created snapshot", manifests)
+	}
+
+	restored, err := m.Restore(created.Name + extension)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Name != created.Name {
+		t.Fatalf("Restore() = %+v, want %s", restored, created.Name)
+	}
+	if !indexed(t, engine, "before") || indexed(t, engine, "after") {
+		t.Fatal("restored index does not hold the patents of the snapshot")
+	}
+	patents, err := engine.SearchAndRetrievePatents(context.Background(), "chair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patents) != 1 || patents[0].PatentStorageID != "before" {
+		t.Fatalf("search after restore = %+v", patents)
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	dir := t.TempDir()
+	engine := newEngine(t, filepath.Join(dir, "index"))
+	snapshots := filepath.Join(dir, "snapshots")
+	m := NewManager(snapshots, engine)
+
+	if err := os.MkdirAll(snapshots, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := &Manifest{Name: "snapshot-old", CreatedAt: time.Now(), MappingVersion: indexer.MappingVersion + 1}
+	if err := writeArchive(filepath.Join(snapshots, old.Name+extension), old, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(snapshots, "snapshot-broken"+extension), []byte("not a tarball"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{"", ErrInvalidSnapshot},
+		{"../snapshot", ErrInvalidSnapshot},
+		{".restore-1", ErrInvalidSnapshot},
+		{"snapshot-missing", ErrNotFound},
+		{"snapshot-broken", ErrInvalidSnapshot},
+		{"snapshot-old", ErrMappingVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Restore(tt.name); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Restore(%q) = %v, want %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir, newEngine(t, ""))
+
+	if _, err := m.Create(); !errors.Is(err, indexer.ErrSnapshotUnsupported) {
+		t.Fatalf("Create() = %v, want %v", err, indexer.ErrSnapshotUnsupported)
+	}
+	manifest := &Manifest{Name: "snapshot-1", CreatedAt: time.Now(), MappingVersion: indexer.MappingVersion}
+	if err := writeArchive(filepath.Join(dir, manifest.Name+extension), manifest, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Restore(manifest.Name); !errors.Is(err, indexer.ErrSnapshotUnsupported) {
+		t.Fatalf("Restore() = %v, want %v", err, indexer.ErrSnapshotUnsupported)
+	}
+}
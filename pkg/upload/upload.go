@@ -0,0 +1,131 @@
+package upload
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kinds of uploaded files.
+const (
+	KindXML   = "xml"
+	KindZip   = "zip"
+	KindTarGz = "tar.gz"
+)
+
+var (
+	// ErrUnsupportedType is returned for files that are not .tar.gz, .zip or .xml.
+	ErrUnsupportedType = errors.New("unsupported file type, expected .tar.gz, .zip or .xml")
+	// ErrTooLarge is returned when an upload exceeds the size limit.
+	ErrTooLarge = errors.New("upload exceeds the size limit")
+	// ErrChecksumMismatch is returned when an upload does not match the
+	// checksum sent by the client.
+	ErrChecksumMismatch = errors.New("upload does not match the expected SHA-256 checksum")
+)
+
+// File is an upload written to storage.
+type File struct {
+	Name   string `json:"name"`
+	Path   string `json:"-"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Kind returns the kind of a file from its name, or "" if it is not supported.
+func Kind(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return KindTarGz
+	case strings.HasSuffix(name, ".zip"):
+		return KindZip
+	case strings.HasSuffix(name, ".xml"):
+		return KindXML
+	}
+	return ""
+}
+
+// Store writes uploads to a directory, each in a directory of its own so that
+// extracting an archive never touches another upload.
+type Store struct {
+	dir      string
+	maxBytes int64
+}
+
+func NewStore(dir string, maxBytes int64) *Store {
+	return &Store{dir: dir, maxBytes: maxBytes}
+}
+
+// MaxBytes returns the size limit of a single upload.
+func (s *Store) MaxBytes() int64 {
+	return s.maxBytes
+}
+
+// Save streams r to storage under name while computing its SHA-256 checksum.
+// When expectedSHA256 is set, the upload is rejected unless it matches. A
+// rejected upload is removed from storage.
+func (s *Store) Save(name string, r io.Reader, expectedSHA256 string) (*File, error) {
+	name = filepath.Base(filepath.Clean("/" + name))
+	if Kind(name) == "" {
+		return nil, ErrUnsupportedType
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(s.dir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating directory %s: %w", dir, err)
+	}
+
+	file, err := s.write(filepath.Join(dir, name), r, expectedSHA256)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	file.Name = name
+	return file, nil
+}
+
+func (s *Store) write(path string, r io.Reader, expectedSHA256 string) (*File, error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating file at %s: %w", path, err)
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	// Read one byte past the limit to tell a file of exactly maxBytes from a
+	// larger one.
+	n, err := io.Copy(io.MultiWriter(out, hash), io.LimitReader(r, s.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("writing to file at %s: %w", path, err)
+	}
+	if n > s.maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if expectedSHA256 != "" && !strings.EqualFold(expectedSHA256, sum) {
+		return nil, ErrChecksumMismatch
+	}
+	if err := out.Close(); err != nil {
+		return nil, fmt.Errorf("closing file at %s: %w", path, err)
+	}
+	return &File{Path: path, Size: n, SHA256: sum}, nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating upload ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/avyukth/search-app/pkg/database/mongo"
+	"github.com/avyukth/search-app/pkg/events"
+)
+
+// progressInterval is the shortest time between two progress events of a job.
+// State changes and the end of the job are always published.
+const progressInterval = 250 * time.Millisecond
+
+// jobProgress records the progress of a task in its job and publishes it to
+// the event broker. Tasks enqueued without a job have a nil jobProgress,
+// which records nothing. Failures to record progress are logged and do not
+// stop the task.
+type jobProgress struct {
+	db     *mongo.Database
+	broker *events.Broker
+	id     string
+
+	mu           sync.Mutex
+	state        string
+	counters     mongo.JobCounters
+	lastProgress time.Time
+}
+
+func (w *taskWorker) trackJob(id string) *jobProgress {
+	if id == "" {
+		return nil
+	}
+	return &jobProgress{db: w.dbClient, broker: w.events, id: id, state: mongo.JobQueued}
+}
+
+func (j *jobProgress) stage(state string) {
+	if j == nil {
+		return
+	}
+	if err := j.db.UpdateJobState(j.id, state, nil); err != nil {
+		log.Printf("Error updating job %s to %s: %v", j.id, state, err)
+	}
+
+	j.mu.Lock()
+	j.state = state
+	event := j.event(events.StateEvent, nil)
+	j.mu.Unlock()
+	j.publish(event)
+}
+
+func (j *jobProgress) count(delta mongo.JobCounters) {
+	if j == nil {
+		return
+	}
+	if err := j.db.IncrementJobCounters(j.id, delta); err != nil {
+		log.Printf("Error updating counters of job %s: %v", j.id, err)
+	}
+
+	j.mu.Lock()
+	j.counters.FilesSeen += delta.FilesSeen
+	j.counters.FilesParsed += delta.FilesParsed
+	j.counters.FilesFailed += delta.FilesFailed
+	j.counters.FilesIndexed += delta.FilesIndexed
+	if time.Since(j.lastProgress) < progressInterval {
+		j.mu.Unlock()
+		return
+	}
+	j.lastProgress = time.Now()
+	event := j.event(events.ProgressEvent, nil)
+	j.mu.Unlock()
+	j.publish(event)
+}
+
+func (j *jobProgress) finish(err error) {
+	if j == nil {
+		return
+	}
+	state := mongo.JobDone
+	if err != nil {
+		state = mongo.JobFailed
+	}
+	if updateErr := j.db.UpdateJobState(j.id, state, err); updateErr != nil {
+		log.Printf("Error updating job %s to %s: %v", j.id, state, updateErr)
+	}
+
+	j.mu.Lock()
+	j.state = state
+	event := j.event(events.StateEvent, err)
+	j.mu.Unlock()
+	j.publish(event)
+}
+
+// event builds an event from the current state and counters. j.mu must be held.
+func (j *jobProgress) event(eventType string, err error) events.Event {
+	event := events.Event{
+		Type:     eventType,
+		JobID:    j.id,
+		State:    j.state,
+		Counters: j.counters,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
+
+func (j *jobProgress) publish(event events.Event) {
+	if j.broker != nil {
+		j.broker.Publish(event)
+	}
+}
@@ -6,16 +6,31 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/avyukth/search-app/pkg/alert"
 	"github.com/avyukth/search-app/pkg/database/mongo"
 	"github.com/avyukth/search-app/pkg/downloader"
+	"github.com/avyukth/search-app/pkg/events"
 	"github.com/avyukth/search-app/pkg/indexer"
 	"github.com/avyukth/search-app/pkg/parser"
 	"github.com/avyukth/search-app/pkg/queue"
+	"github.com/avyukth/search-app/pkg/upload"
 )
 
+// parseConcurrency is the number of files of a task parsed at the same time.
+var parseConcurrency = runtime.NumCPU()
+
+// indexBatchSize is the number of patents indexed together.
+const indexBatchSize = 100
+
+// downloadTimeout bounds the download of a link. Parsing and indexing a bulk
+// file can take much longer, and only stop when the task is cancelled.
+const downloadTimeout = 100 * time.Second
+
 type Worker interface {
 	Process(ctx context.Context, task queue.Task) error
 }
@@ -24,47 +39,94 @@ type taskWorker struct {
 	downloader downloader.Downloader
 	parser     *parser.Parser
 	dbClient   *mongo.Database
-	indexer    *indexer.SearchEngine
+	indexer    indexer.SearchBackend
+	alerts     *alert.Evaluator
+	events     *events.Broker
 }
 
-func NewWorker(d downloader.Downloader, p *parser.Parser, db *mongo.Database, i *indexer.SearchEngine) Worker {
+func NewWorker(d downloader.Downloader, p *parser.Parser, db *mongo.Database, i indexer.SearchBackend, a *alert.Evaluator, b *events.Broker) Worker {
 	return &taskWorker{
 		downloader: d,
 		parser:     p,
 		dbClient:   db,
 		indexer:    i,
+		alerts:     a,
+		events:     b,
 	}
 }
 
 func (w *taskWorker) Process(ctx context.Context, task queue.Task) error {
+	job := w.trackJob(task.JobID)
+
+	var err error
 	switch task.Type {
 	case queue.DownloadAndProcess:
-		return w.DownExtractAndProcess(ctx, task)
+		err = w.DownExtractAndProcess(ctx, task, job)
 	case queue.WalkAndProcess:
-		return w.walkDir(task.FilePath)
+		err = w.walkDir(ctx, task.FilePath, job)
+	case queue.UploadAndProcess:
+		err = w.processUpload(ctx, task, job)
 	default:
-		return fmt.Errorf("unsupported task type: %v", task.Type)
+		err = fmt.Errorf("unsupported task type: %v", task.Type)
 	}
+
+	job.finish(err)
+	return err
 }
 
-func (w *taskWorker) DownExtractAndProcess(ctx context.Context, task queue.Task) error {
+func (w *taskWorker) DownExtractAndProcess(ctx context.Context, task queue.Task, job *jobProgress) error {
 	log.Printf("Starting processing for task: %+v", task)
-	filePath, err := w.downloader.Download(ctx, task.FilePath)
+	job.stage(mongo.JobDownloading)
+	downloadCtx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	filePath, err := w.downloader.Download(downloadCtx, task.FilePath)
+	cancel()
 	if err != nil {
 		return err
 	}
 	log.Printf("Successfully downloaded file to: %s", filePath)
 
+	job.stage(mongo.JobExtracting)
 	extractedPath, err := w.downloader.ExtractTarGz(filePath)
 	if err != nil {
 		return err
 	}
-	return w.walkDir(extractedPath)
+	return w.walkDir(ctx, extractedPath, job)
 }
 
-func (w *taskWorker) walkDir(dirPath string) error {
-	var wg sync.WaitGroup
+// processUpload extracts an uploaded archive next to it and ingests the XML
+// files it contains. An uploaded XML file is ingested as is.
+func (w *taskWorker) processUpload(ctx context.Context, task queue.Task, job *jobProgress) error {
+	log.Printf("Starting processing for task: %+v", task)
 
+	var extract func(string) (string, error)
+	switch upload.Kind(task.FilePath) {
+	case upload.KindXML:
+		return w.walkDir(ctx, task.FilePath, job)
+	case upload.KindZip:
+		extract = w.downloader.ExtractZip
+	case upload.KindTarGz:
+		extract = w.downloader.ExtractTarGz
+	default:
+		return fmt.Errorf("processing upload %s: %w", task.FilePath, upload.ErrUnsupportedType)
+	}
+
+	job.stage(mongo.JobExtracting)
+	extractedPath, err := extract(task.FilePath)
+	if err != nil {
+		return err
+	}
+	return w.walkDir(ctx, extractedPath, job)
+}
+
+// walkDir ingests the XML files under dirPath. Files are parsed and stored
+// parseConcurrency at a time, and the parsed patents indexed in batches of
+// indexBatchSize as they come, so that memory does not grow with the size of
+// the archive. The job moves to the indexing state once every file is parsed.
+// A file that fails is logged and counted, and does not fail the job.
+func (w *taskWorker) walkDir(ctx context.Context, dirPath string, job *jobProgress) error {
+	job.stage(mongo.JobParsing)
+
+	var paths []string
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			log.Printf("Error accessing path %q: %v\n", path, err)
@@ -85,49 +147,117 @@ func (w *taskWorker) walkDir(dirPath string) error {
 		if !strings.HasSuffix(strings.ToLower(info.Name()), ".xml") {
 			return nil
 		}
-
-		wg.Add(1)
-		go func(filePath string) {
-			defer wg.Done()
-			if err := w.processFile(filePath); err != nil {
-				log.Printf("Error processing file at %s: %v", filePath, err)
-			}
-		}(path)
-
+		paths = append(paths, path)
 		return nil
 	})
-
 	if err != nil {
-		log.Printf("Error walking the path %v: %v\n", dirPath, err)
+		return fmt.Errorf("walking the path %v: %w", dirPath, err)
+	}
+	job.count(mongo.JobCounters{FilesSeen: int64(len(paths))})
+
+	batch := make([]*mongo.Patent, 0, indexBatchSize)
+	for patent := range w.parseFiles(ctx, paths, job) {
+		// Parsers still running when the task is cancelled are waited for,
+		// but what they parse is not indexed.
+		if ctx.Err() != nil {
+			continue
+		}
+		batch = append(batch, patent)
+		if len(batch) == indexBatchSize {
+			w.indexPatents(batch, job)
+			batch = make([]*mongo.Patent, 0, indexBatchSize)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	wg.Wait()
+	job.stage(mongo.JobIndexing)
+	if len(batch) > 0 {
+		w.indexPatents(batch, job)
+	}
 	return nil
 }
 
-func (w *taskWorker) processFile(filePath string) error {
+// parseFiles parses and stores the files at paths, parseConcurrency at a
+// time, and sends the patents that were parsed successfully. The channel is
+// closed once every file is parsed, or ctx is cancelled and the parsers are
+// done.
+func (w *taskWorker) parseFiles(ctx context.Context, paths []string, job *jobProgress) <-chan *mongo.Patent {
+	var wg sync.WaitGroup
+	files := make(chan string)
+	patents := make(chan *mongo.Patent, parseConcurrency)
+	for i := 0; i < parseConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filePath := range files {
+				patent, err := w.parseFile(filePath)
+				if err != nil {
+					log.Printf("Error processing file at %s: %v", filePath, err)
+					job.count(mongo.JobCounters{FilesFailed: 1})
+					continue
+				}
+				job.count(mongo.JobCounters{FilesParsed: 1})
+				patents <- patent
+			}
+		}()
+	}
+
+	go func() {
+		for _, path := range paths {
+			if ctx.Err() != nil {
+				break
+			}
+			files <- path
+		}
+		close(files)
+		wg.Wait()
+		close(patents)
+	}()
+	return patents
+}
+
+// parseFile stores the XML document at filePath and the patent parsed from it.
+func (w *taskWorker) parseFile(filePath string) (*mongo.Patent, error) {
 	parsedData, err := w.parser.Parse(filePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	xmlID, err := w.dbClient.StoreXML(parsedData)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	patent, err := w.parser.ParseToStruct(filePath, xmlID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	_, err = w.dbClient.StorePatent(patent)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return patent, nil
+}
+
+// indexPatents indexes a batch of stored patents and evaluates the saved
+// searches against them. A batch that fails is counted as failed files.
+func (w *taskWorker) indexPatents(patents []*mongo.Patent, job *jobProgress) {
+	if err := w.indexer.IndexPatents(patents); err != nil {
+		log.Printf("Error indexing %d patents: %v", len(patents), err)
+		job.count(mongo.JobCounters{FilesFailed: int64(len(patents))})
+		return
 	}
+	log.Printf("Successfully indexed %d patents", len(patents))
+	job.count(mongo.JobCounters{FilesIndexed: int64(len(patents))})
 
-	if err := w.indexer.IndexPatent(patent); err != nil {
-		return err
+	if w.alerts == nil {
+		return
+	}
+	for _, patent := range patents {
+		if err := w.alerts.Evaluate(patent); err != nil {
+			log.Printf("Error evaluating saved searches for patent %s: %v", patent.PatentNumber, err)
+		}
 	}
-	log.Printf("Successfully indexed the patent")
-	return nil
 }